@@ -0,0 +1,36 @@
+package timestate
+
+import (
+	"context"
+	"slices"
+)
+
+// sendBatches delivers the items expired by one check as batches of at most
+// batchSize events. Returns the number of batches not received in time and
+// values of recovered panics. Must be called without the lock.
+func (m *Monitor[K, T]) sendBatches(ctx context.Context, fired []*item[K, T]) (dropped uint64, panics []any) {
+	if m.batchCh == nil || len(fired) == 0 {
+		return 0, nil
+	}
+
+	events := make([]Expired[K, T], len(fired))
+	for i, it := range fired {
+		events[i] = it.expired()
+	}
+
+	size := m.batchSize
+	if size <= 0 {
+		size = len(events)
+	}
+
+	for batch := range slices.Chunk(events, size) {
+		sent, r := send(ctx, m.batchCh, batch, m.timeout())
+		if r != nil {
+			panics = append(panics, r)
+		} else if !sent && ctx.Err() == nil {
+			dropped++
+		}
+	}
+
+	return dropped, panics
+}
@@ -0,0 +1,22 @@
+package timestate
+
+// Change describes a state value transition.
+type Change[K comparable, T any] struct {
+	Key     K    // Changed key
+	Old     T    // Previous value (zero if the state is new)
+	New     T    // Current value
+	Existed bool // Whether the state was tracked before
+}
+
+// changed sends a change notification without blocking: if the channel is
+// full, the notification is dropped. Must be called with the lock held.
+func (m *Monitor[K, T]) changed(change Change[K, T]) {
+	if m.changesCh == nil {
+		return
+	}
+
+	select {
+	case m.changesCh <- change:
+	default: // channel full
+	}
+}
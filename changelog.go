@@ -0,0 +1,125 @@
+package timestate
+
+// Op is a changelog operation.
+type Op int
+
+const (
+	// OpPut means a state was added or changed.
+	OpPut Op = iota
+	// OpDelete means a state is no longer tracked: it expired, was removed
+	// or evicted.
+	OpDelete
+)
+
+// Record is a changelog entry. Records are numbered consecutively starting
+// from 1, so a follower can detect gaps.
+type Record[K comparable, T any] struct {
+	Seq   uint64      // Sequence number
+	Op    Op          // Operation
+	Entry Entry[K, T] // State after OpPut; only Key is set for OpDelete
+}
+
+// defaultLogPending is the changelog queue limit used by WithChangelog
+// for zero or negative values.
+const defaultLogPending = 1024
+
+// record queues a changelog record for shipLog, starting it if it is not
+// running. A record that does not fit the queue is dropped, leaving a gap
+// in the sequence numbers. Nothing is recorded after the monitor stopped.
+// Must be called with the lock held.
+func (m *Monitor[K, T]) record(op Op, entry Entry[K, T]) {
+	if m.changelog == nil || m.stopped {
+		return
+	}
+
+	m.logSeq++
+
+	m.logMu.Lock()
+	defer m.logMu.Unlock()
+
+	if len(m.logQueue) >= m.logPending {
+		return // the follower sees the gap
+	}
+
+	m.logQueue = append(m.logQueue, Record[K, T]{Seq: m.logSeq, Op: op, Entry: entry})
+
+	if !m.logSending {
+		m.logSending = true
+
+		go m.shipLog()
+	}
+}
+
+// shipLog sends queued changelog records in order, waiting for the
+// receiver without holding the monitor lock. It exits when the queue is
+// empty or the monitor stops, discarding records not sent by then.
+func (m *Monitor[K, T]) shipLog() {
+	for {
+		m.logMu.Lock()
+		queue := m.logQueue
+		m.logQueue = nil
+
+		if len(queue) == 0 {
+			m.logSending = false
+			m.logMu.Unlock()
+
+			return
+		}
+
+		m.logMu.Unlock()
+
+		for _, rec := range queue {
+			select {
+			case m.changelog <- rec:
+			case <-m.logStop:
+				return
+			}
+		}
+	}
+}
+
+// Apply replays a changelog record produced by another monitor, so this
+// monitor mirrors its states, e.g. as a hot standby. Deadlines are copied
+// as is. A follower should not be started, so its states disappear only
+// when the leader reports them gone.
+func (m *Monitor[K, T]) Apply(rec Record[K, T]) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := rec.Entry
+	it, exists := m.items[entry.Key]
+
+	switch {
+	case rec.Op == OpDelete:
+		if exists {
+			m.remove(it)
+		}
+	case !exists:
+		if m.insert(entry.Key, entry.Value, m.defaultTTL, entry.Expires, entry.Absolute) && entry.Pinned {
+			m.pin(m.items[entry.Key])
+		}
+	default:
+		if it.pinned && !entry.Pinned {
+			m.unpin(it, entry.Expires)
+		}
+
+		it.absolute = entry.Absolute
+		m.update(it, entry.Value, m.defaultTTL, entry.Expires, entry.Absolute)
+
+		if entry.Pinned && !it.pinned {
+			m.pin(it)
+		}
+	}
+}
+
+// persist reports an added or changed item to the changelog, if any.
+// Must be called with the lock held.
+func (m *Monitor[K, T]) persist(it *item[K, T]) {
+	m.record(OpPut, it.entry())
+}
+
+// unpersist reports a key that is no longer tracked to the changelog,
+// if any. Must be called with the lock held.
+func (m *Monitor[K, T]) unpersist(key K) {
+	m.record(OpDelete, Entry[K, T]{Key: key})
+}
@@ -0,0 +1,131 @@
+package timestate_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mdigger/timestate"
+)
+
+func TestChangelog(t *testing.T) {
+	changelog := make(chan timestate.Record[string, int], 100)
+	leader := timestate.New(time.Second, time.Minute, make(chan string, 10),
+		timestate.WithChangelog(changelog, 0),
+	)
+	follower := timestate.New[string, int](time.Second, time.Minute, nil)
+
+	leader.Watch("a", 1)
+	leader.Watch("a", 2)
+	leader.WatchUntil("b", 1, time.Now().Add(time.Hour))
+	leader.Watch("c", 1)
+	leader.Pin("c")
+	leader.Watch("c", 2)
+	leader.Watch("d", 1)
+	leader.Remove("d")
+	leader.WatchWithTTL("e", 1, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	leader.Flush()
+
+	var seq uint64
+
+	for received := true; received; {
+		select {
+		case rec := <-changelog:
+			if seq++; rec.Seq != seq {
+				t.Fatalf("Unexpected sequence number %d, want %d", rec.Seq, seq)
+			}
+
+			follower.Apply(rec)
+		case <-time.After(100 * time.Millisecond):
+			received = false
+		}
+	}
+
+	want, _ := json.Marshal(leader)
+	got, _ := json.Marshal(follower)
+
+	if string(got) != string(want) {
+		t.Errorf("Follower diverged:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestChangelogSlowFollower(t *testing.T) {
+	changelog := make(chan timestate.Record[string, int]) // nobody receives yet
+	leader := timestate.New(time.Second, time.Minute, make(chan string, 10),
+		timestate.WithChangelog(changelog, 0),
+	)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		for i := range 10 {
+			leader.Watch("key", i)
+			leader.Get("key")
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("A slow follower should not block the leader")
+	}
+
+	for want := uint64(1); want <= 10; want++ {
+		if rec := <-changelog; rec.Seq != want || rec.Entry.Value != int(want-1) {
+			t.Fatalf("Unexpected record %+v, want seq %d", rec, want)
+		}
+	}
+}
+
+func TestChangelogOverflow(t *testing.T) {
+	changelog := make(chan timestate.Record[string, int]) // nobody receives yet
+	leader := timestate.New(time.Second, time.Minute, make(chan string, 10),
+		timestate.WithChangelog(changelog, 2),
+	)
+
+	for i := range 10 {
+		leader.Watch("key", i)
+	}
+
+	var seqs []uint64
+
+	for received := true; received; {
+		select {
+		case rec := <-changelog:
+			seqs = append(seqs, rec.Seq)
+		case <-time.After(100 * time.Millisecond):
+			received = false
+		}
+	}
+
+	// one batch taken by the sender plus a full queue
+	if len(seqs) == 0 || len(seqs) > 4 || seqs[0] != 1 {
+		t.Fatalf("Unexpected records: %v", seqs)
+	}
+
+	leader.Watch("key", 10)
+
+	if rec := <-changelog; rec.Seq != 11 {
+		t.Errorf("Expected a gap up to 11, got %d after %v", rec.Seq, seqs)
+	}
+}
+
+func TestChangelogStop(t *testing.T) {
+	changelog := make(chan timestate.Record[string, int]) // nobody receives
+	leader := timestate.New(time.Second, time.Minute, make(chan string, 10),
+		timestate.WithChangelog(changelog, 0),
+	)
+	leader.Start(t.Context())
+	leader.Watch("a", 1)
+	leader.Watch("b", 1)
+	leader.Stop()
+	time.Sleep(10 * time.Millisecond) // let the sender exit
+
+	select {
+	case rec := <-changelog:
+		t.Errorf("Record sent after Stop: %+v", rec)
+	default:
+	}
+}
@@ -0,0 +1,76 @@
+package timestate_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mdigger/timestate"
+)
+
+func TestFakeClock(t *testing.T) {
+	clock := timestate.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	expiredCh := make(chan string, 1)
+	monitor := timestate.New(time.Second, 5*time.Minute, expiredCh,
+		timestate.WithClock[string, int](clock),
+	)
+
+	monitor.Watch("key", 1)
+
+	if _, expires, _ := monitor.Get("key"); !expires.Equal(clock.Now().Add(5 * time.Minute)) {
+		t.Errorf("Deadline should use the fake clock: %v", expires)
+	}
+
+	clock.Advance(4 * time.Minute)
+
+	if n := monitor.Flush(); n != 0 {
+		t.Fatalf("State expired too early: %d", n)
+	}
+
+	clock.Advance(time.Minute)
+
+	if n := monitor.Flush(); n != 1 {
+		t.Fatalf("State did not expire: %d", n)
+	}
+}
+
+func TestFakeClockTicker(t *testing.T) {
+	clock := timestate.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	expiredCh := make(chan string, 1)
+	monitor := timestate.New(time.Second, 5*time.Minute, expiredCh,
+		timestate.WithClock[string, int](clock),
+	)
+	ctx := t.Context()
+	monitor.Start(ctx)
+
+	monitor.Watch("key", 1)
+	clock.Advance(5*time.Minute + time.Second)
+
+	select {
+	case <-expiredCh:
+	case <-time.After(time.Second):
+		t.Error("Fake ticker did not trigger the check")
+	}
+}
+
+func TestTTL(t *testing.T) {
+	clock := timestate.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	monitor := timestate.New(time.Second, 5*time.Minute, make(chan string, 1),
+		timestate.WithClock[string, int](clock),
+	)
+	monitor.Watch("key", 1)
+	clock.Advance(2 * time.Minute)
+
+	if ttl, ok := monitor.TTL("key"); !ok || ttl != 3*time.Minute {
+		t.Errorf("Unexpected TTL: %v, %v", ttl, ok)
+	}
+
+	clock.Advance(time.Hour)
+
+	if ttl, ok := monitor.TTL("key"); !ok || ttl != 0 {
+		t.Errorf("Overdue state should report zero: %v, %v", ttl, ok)
+	}
+
+	if _, ok := monitor.TTL("missing"); ok {
+		t.Error("Untracked state reported")
+	}
+}
@@ -0,0 +1,51 @@
+package timestate
+
+// defaultCompactRatio is the unused share of the heap capacity that
+// triggers compaction unless changed by WithCompactionThreshold.
+const defaultCompactRatio = 0.5
+
+// minCompactCap is the heap capacity below which compaction is not worth
+// the reallocation.
+const minCompactCap = 64
+
+// compactIfNeeded reallocates the heap backing array when most of its
+// capacity is no longer used after states were removed or expired.
+// Must be called with the lock held.
+func (m *Monitor[K, T]) compactIfNeeded() {
+	if m.compactRatio <= 0 || cap(m.heap) < minCompactCap ||
+		float64(cap(m.heap)-len(m.heap)) <= m.compactRatio*float64(cap(m.heap)) {
+		return
+	}
+
+	m.compact()
+}
+
+// compact shrinks the heap backing array to its length. The order and
+// the stored positions are not changed. Must be called with the lock held.
+func (m *Monitor[K, T]) compact() {
+	m.heap = append(make(items[K, T], 0, len(m.heap)), m.heap...)
+}
+
+// Compact releases memory left over from removed and expired states:
+// the heap backing arrays are shrunk to their length and the storage map,
+// which never shrinks by itself, is rebuilt. Useful for long-running
+// services after a burst of states is gone. Costs O(n).
+func (m *Monitor[K, T]) Compact() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.compact()
+
+	if m.maxSize > 0 {
+		m.byPriority = append(make(priorityItems[K, T], 0, len(m.byPriority)), m.byPriority...)
+	}
+
+	m.warnings = append(make(warnItems[K, T], 0, len(m.warnings)), m.warnings...)
+
+	items := make(map[K]*item[K, T], len(m.items))
+	for key, it := range m.items {
+		items[key] = it
+	}
+
+	m.items = items
+}
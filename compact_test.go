@@ -0,0 +1,52 @@
+package timestate_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mdigger/timestate"
+)
+
+// churn watches and removes batch states per round, running a check after
+// each round, and returns the largest heap capacity seen after a check.
+func churn(m *timestate.Monitor[int, int], rounds, batch int) int {
+	var peak int
+
+	for r := range rounds {
+		for i := range batch {
+			m.Watch(r*batch+i, i)
+		}
+
+		for i := range batch {
+			m.Remove(r*batch + i)
+		}
+
+		m.Flush()
+		peak = max(peak, timestate.HeapCap(m))
+	}
+
+	return peak
+}
+
+func TestCompactionBoundsHeap(t *testing.T) {
+	m := timestate.New[int, int](time.Second, time.Hour, make(chan int, 1))
+
+	if peak := churn(m, 100, 1000); peak > 64 {
+		t.Errorf("Heap capacity not released under churn: %d", peak)
+	}
+
+	if m.Len() != 0 {
+		t.Errorf("Unexpected states left: %d", m.Len())
+	}
+}
+
+func BenchmarkRemoveChurn(b *testing.B) {
+	m := timestate.New[int, int](time.Second, time.Hour, make(chan int, 1))
+
+	peak := churn(m, b.N, 1000)
+	if peak > 64 {
+		b.Fatalf("Heap capacity not released under churn: %d", peak)
+	}
+
+	b.ReportMetric(float64(peak), "heap-cap")
+}
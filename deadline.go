@@ -0,0 +1,84 @@
+package timestate
+
+import (
+	"context"
+	"time"
+)
+
+// runDeadlines is the background checker used with WithDeadlineTimer.
+// Instead of polling, it sleeps until the earliest deadline and is woken
+// up when an earlier one is scheduled, so an idle monitor does no work.
+func (m *Monitor[K, T]) runDeadlines(ctx context.Context) {
+	m.mu.Lock()
+	wait, ok := m.nextCheck(false)
+	m.mu.Unlock()
+
+	for {
+		var (
+			timer Timer
+			fired <-chan time.Time // nil blocks forever while idle
+		)
+
+		if ok {
+			timer = m.clock.NewTimer(wait)
+			fired = timer.C()
+		}
+
+		select {
+		case <-fired:
+			m.drain(ctx)
+
+			m.mu.Lock()
+			wait, ok = m.nextCheck(true)
+			m.mu.Unlock()
+		case <-m.rearmCh:
+			m.mu.Lock()
+			wait, ok = m.nextCheck(false)
+			m.mu.Unlock()
+		case <-ctx.Done():
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// nextCheck returns how long to sleep until the next check, or false if
+// there is nothing to wait for. Deadlines still due after a check belong
+// to notifications that could not be delivered and are retried after the
+// check interval, as are pending warnings.
+// Must be called with the lock held.
+func (m *Monitor[K, T]) nextCheck(afterCheck bool) (time.Duration, bool) {
+	if m.heap.Len() == 0 {
+		return 0, false
+	}
+
+	wait := m.heap[0].Expires.Sub(m.clock.Now())
+	if wait <= 0 && afterCheck {
+		wait = m.checkInterval
+	}
+
+	if m.warnCh != nil || m.warnFunc != nil {
+		wait = min(wait, m.checkInterval)
+	}
+
+	return max(wait, 0), true
+}
+
+// rearm wakes up the deadline timer if the item became the earliest one.
+// Must be called with the lock held.
+func (m *Monitor[K, T]) rearm(it *item[K, T]) {
+	if m.rearmCh == nil || it.index != 0 {
+		return
+	}
+
+	select {
+	case m.rearmCh <- struct{}{}:
+	default: // already pending
+	}
+}
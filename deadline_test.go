@@ -0,0 +1,63 @@
+package timestate_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mdigger/timestate"
+)
+
+func TestDeadlineTimer(t *testing.T) {
+	clock := timestate.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	expiredCh := make(chan string, 2)
+	monitor := timestate.New(time.Hour, time.Minute, expiredCh,
+		timestate.WithClock[string, int](clock),
+		timestate.WithDeadlineTimer[string, int](),
+	)
+	monitor.Start(t.Context())
+
+	monitor.Watch("late", 1)
+	monitor.WatchWithTTL("early", 2, 5*time.Second) // earlier deadline rearms the timer
+
+	clock.Advance(5 * time.Second)
+
+	select {
+	case key := <-expiredCh:
+		if key != "early" {
+			t.Errorf("Expected early, got %s", key)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("State did not expire before the check interval")
+	}
+
+	clock.Advance(time.Minute)
+
+	select {
+	case key := <-expiredCh:
+		if key != "late" {
+			t.Errorf("Expected late, got %s", key)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timer was not armed for the next deadline")
+	}
+}
+
+func TestDeadlineTimerRealClock(t *testing.T) {
+	expiredCh := make(chan string, 1)
+	monitor := timestate.New(time.Hour, time.Hour, expiredCh,
+		timestate.WithDeadlineTimer[string, int](),
+	)
+	monitor.Start(t.Context())
+
+	start := time.Now()
+	monitor.WatchWithTTL("key", 1, 20*time.Millisecond)
+
+	select {
+	case <-expiredCh:
+		if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+			t.Errorf("State expired too early: %v", elapsed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("State did not expire")
+	}
+}
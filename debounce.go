@@ -0,0 +1,26 @@
+package timestate
+
+import "time"
+
+// debounced reports whether an expiration of key at now falls within the
+// debounce window of its previous notification.
+// Must be called with the lock held.
+func (m *Monitor[K, T]) debounced(key K, now time.Time) bool {
+	if m.debounce <= 0 {
+		return false
+	}
+
+	last, ok := m.lastExpired[key]
+
+	return ok && now.Sub(last) < m.debounce
+}
+
+// pruneDebounce forgets expirations older than the debounce window, so the
+// map only holds keys expired within it. Must be called with the lock held.
+func (m *Monitor[K, T]) pruneDebounce(now time.Time) {
+	for key, last := range m.lastExpired {
+		if now.Sub(last) >= m.debounce {
+			delete(m.lastExpired, key)
+		}
+	}
+}
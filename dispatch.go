@@ -0,0 +1,116 @@
+package timestate
+
+import "context"
+
+// enqueue queues expired items for the dispatcher started by Start.
+// Returns false if no dispatcher is running, e.g. it stopped during the
+// check, so the caller must deliver the queue with flushOutbox. Queued
+// items are never discarded: Shutdown delivers what is left.
+func (m *Monitor[K, T]) enqueue(fired []*item[K, T]) bool {
+	m.outMu.Lock()
+	defer m.outMu.Unlock()
+
+	m.outbox = append(m.outbox, fired...)
+
+	if !m.dispatching {
+		return false
+	}
+
+	select {
+	case m.outWake <- struct{}{}:
+	default: // already signaled
+	}
+
+	return true
+}
+
+// dispatch delivers queued expiration notifications until ctx is done,
+// waiting for the receiver without holding any lock. Notifications not
+// delivered by then stay queued for Shutdown.
+func (m *Monitor[K, T]) dispatch(ctx context.Context) {
+	for {
+		select {
+		case <-m.outWake:
+		case <-ctx.Done():
+			m.outMu.Lock()
+			m.dispatching = false
+			m.outMu.Unlock()
+
+			return
+		}
+
+		m.outMu.Lock()
+		queue := m.outbox
+		m.outbox = nil
+		m.outMu.Unlock()
+
+		if rest := m.deliver(ctx, queue); len(rest) > 0 {
+			m.outMu.Lock()
+			m.outbox = append(rest, m.outbox...)
+			m.outMu.Unlock()
+		}
+	}
+}
+
+// deliver sends notifications for the items in order, blocking until each
+// one is received, and returns the items left when ctx is done.
+func (m *Monitor[K, T]) deliver(ctx context.Context, queue []*item[K, T]) []*item[K, T] {
+	m.mu.RLock()
+	ch := m.expiredCh
+	m.mu.RUnlock()
+
+	for i, it := range queue {
+		var (
+			sent = true
+			r    any
+		)
+
+		switch {
+		case m.eventCh != nil:
+			sent, r = send(ctx, m.eventCh, it.expired(), nil)
+		case ch != nil:
+			sent, r = send(ctx, ch, it.Key, nil)
+		}
+
+		if !sent && r == nil {
+			return queue[i:]
+		}
+
+		if r != nil && m.onError != nil {
+			m.onError(r)
+		}
+	}
+
+	return nil
+}
+
+// flushOutbox delivers notifications left by a stopped dispatcher.
+// Returns the ctx error if some of them were not delivered.
+func (m *Monitor[K, T]) flushOutbox(ctx context.Context) error {
+	m.outMu.Lock()
+	queue := m.outbox
+	m.outbox = nil
+	m.outMu.Unlock()
+
+	if rest := m.deliver(ctx, queue); len(rest) > 0 {
+		m.outMu.Lock()
+		m.outbox = append(rest, m.outbox...)
+		m.outMu.Unlock()
+
+		return ctx.Err()
+	}
+
+	return nil
+}
+
+// postpone queues notifications whose blocking delivery was interrupted
+// by Stop, so Shutdown delivers them with flushOutbox.
+func (m *Monitor[K, T]) postpone(unsent []*item[K, T]) {
+	if len(unsent) == 0 {
+		return
+	}
+
+	m.outMu.Lock()
+	m.outbox = append(m.outbox, unsent...)
+	m.outMu.Unlock()
+}
@@ -0,0 +1,53 @@
+package timestate
+
+// Reason tells why a state stopped being tracked or its value was lost.
+type Reason int
+
+const (
+	// ReasonExpired means the state reached its deadline.
+	ReasonExpired Reason = iota
+	// ReasonRemoved means the state was removed explicitly, e.g. by
+	// Remove, Clear or a canceled context.
+	ReasonRemoved
+	// ReasonEvicted means the state was evicted by the size limit.
+	ReasonEvicted
+	// ReasonReplaced means the value was replaced by a different one;
+	// the state itself is still tracked.
+	ReasonReplaced
+)
+
+// String returns the reason name.
+func (r Reason) String() string {
+	switch r {
+	case ReasonExpired:
+		return "expired"
+	case ReasonRemoved:
+		return "removed"
+	case ReasonEvicted:
+		return "evicted"
+	case ReasonReplaced:
+		return "replaced"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes why a value stopped being tracked.
+type Event[K comparable, T any] struct {
+	Key    K      // Affected key
+	Value  T      // Value that is gone
+	Reason Reason // What happened
+}
+
+// emit sends a lifecycle event without blocking: if the channel is full,
+// the event is dropped. Must be called with the lock held.
+func (m *Monitor[K, T]) emit(event Event[K, T]) {
+	if m.eventsCh == nil {
+		return
+	}
+
+	select {
+	case m.eventsCh <- event:
+	default: // channel full
+	}
+}
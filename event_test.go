@@ -0,0 +1,45 @@
+package timestate_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mdigger/timestate"
+)
+
+func TestEvents(t *testing.T) {
+	events := make(chan timestate.Event[string, int], 10)
+	monitor := timestate.New(time.Second, time.Minute, make(chan string, 1),
+		timestate.WithMaxSize[string, int](2),
+		timestate.WithEvents(events),
+	)
+
+	monitor.Watch("a", 1)
+	monitor.Watch("a", 2)
+	monitor.Remove("a")
+	monitor.WatchWithTTL("b", 1, time.Hour)
+	monitor.WatchWithTTL("c", 1, time.Nanosecond)
+	monitor.WatchWithTTL("d", 1, time.Hour) // evicts c
+	monitor.WatchWithTTL("e", 1, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	monitor.Flush()
+
+	want := []timestate.Event[string, int]{
+		{Key: "a", Value: 1, Reason: timestate.ReasonReplaced},
+		{Key: "a", Value: 2, Reason: timestate.ReasonRemoved},
+		{Key: "c", Value: 1, Reason: timestate.ReasonEvicted},
+		{Key: "b", Value: 1, Reason: timestate.ReasonEvicted},
+		{Key: "e", Value: 1, Reason: timestate.ReasonExpired},
+	}
+
+	for _, w := range want {
+		select {
+		case got := <-events:
+			if got != w {
+				t.Errorf("Expected %+v, got %+v", w, got)
+			}
+		default:
+			t.Fatalf("Missing event %+v", w)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package timestate
+
+// CheckInvariants exposes checkInvariants to external tests.
+func CheckInvariants[K, T comparable](m *Monitor[K, T]) error {
+	return m.checkInvariants()
+}
+
+// HeapCap returns the capacity of the expiration heap backing array.
+func HeapCap[K, T comparable](m *Monitor[K, T]) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return cap(m.heap)
+}
@@ -0,0 +1,143 @@
+package timestate
+
+import (
+	"sync"
+	"time"
+)
+
+// FakeClock is a Clock whose time only moves with Advance.
+// Intended for deterministic tests.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+	timers  []*fakeTimer
+}
+
+// NewFakeClock returns a FakeClock set to now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now returns the current fake time.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// Advance moves the time forward by d and fires due tickers and timers.
+// Like the time package, a ticker whose channel was not drained drops
+// ticks instead of blocking.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+
+	for _, t := range c.tickers {
+		if t.period <= 0 {
+			continue // stopped
+		}
+
+		for !t.next.After(c.now) {
+			fire(t.ch, t.next)
+			t.next = t.next.Add(t.period)
+		}
+	}
+
+	active := c.timers[:0]
+
+	for _, t := range c.timers {
+		if t.active && !t.when.After(c.now) {
+			t.active = false
+			fire(t.ch, t.when)
+		}
+
+		if t.active {
+			active = append(active, t)
+		}
+	}
+
+	c.timers = active
+}
+
+// NewTicker returns a ticker driven by Advance.
+func (c *FakeClock) NewTicker(d time.Duration) Ticker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTicker{clock: c, ch: make(chan time.Time, 1), period: d, next: c.now.Add(d)}
+	c.tickers = append(c.tickers, t)
+
+	return t
+}
+
+// NewTimer returns a timer driven by Advance.
+// Like the time package, a non-positive duration fires immediately.
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{clock: c, ch: make(chan time.Time, 1), when: c.now.Add(d), active: true}
+	c.timers = append(c.timers, t)
+
+	if d <= 0 {
+		t.active = false
+		fire(t.ch, t.when)
+	}
+
+	return t
+}
+
+// fire sends a tick without blocking.
+func fire(ch chan time.Time, t time.Time) {
+	select {
+	case ch <- t:
+	default: // tick dropped
+	}
+}
+
+type fakeTicker struct {
+	clock  *FakeClock
+	ch     chan time.Time
+	period time.Duration
+	next   time.Time
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.ch }
+
+func (t *fakeTicker) Reset(d time.Duration) {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	t.period = d
+	t.next = t.clock.now.Add(d)
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	t.period = 0
+}
+
+type fakeTimer struct {
+	clock  *FakeClock
+	ch     chan time.Time
+	when   time.Time
+	active bool
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.ch }
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	active := t.active
+	t.active = false
+
+	return active
+}
@@ -0,0 +1,119 @@
+package timestate
+
+import (
+	"encoding/gob"
+	"errors"
+	"io"
+)
+
+// WriteTo writes all tracked states to w as a gob stream of Entry values,
+// which is much faster and more compact than JSON for large monitors.
+// Entries are encoded one by one while iterating over the states, so no
+// copy of the whole monitor is made. The read lock is held until all of
+// them are written, so a slow w delays changes of the monitor. Both K and
+// T must be gob-encodable. Returns the number of bytes written.
+func (m *Monitor[K, T]) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	enc := gob.NewEncoder(cw)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, it := range m.items {
+		if err := enc.Encode(it.entry()); err != nil {
+			return cw.n, err
+		}
+	}
+
+	return cw.n, nil
+}
+
+// readBatch is the number of decoded entries ReadFrom adds at once.
+const readBatch = 1024
+
+// ReadFrom replaces all tracked states with ones read from r until EOF,
+// as written by WriteTo, keeping their deadlines like Restore. Entries are
+// added in batches of 1024 as they are decoded, so memory use does not
+// double for large streams. The states are replaced only after the first
+// batch is decoded, so an error within it leaves the monitor unchanged. A
+// later error leaves only the entries decoded before it: the previous
+// states are already gone, so the monitor should be reloaded from another
+// checkpoint or cleared. Returns the number of bytes read.
+func (m *Monitor[K, T]) ReadFrom(r io.Reader) (int64, error) {
+	cr := &countingReader{r: r}
+	dec := gob.NewDecoder(cr)
+	batch := make([]Entry[K, T], 0, readBatch)
+	replaced := false
+
+	add := func() {
+		m.mu.Lock()
+		if replaced {
+			m.loadMore(batch)
+		} else {
+			m.load(batch)
+			replaced = true
+		}
+		m.mu.Unlock()
+
+		clear(batch)
+		batch = batch[:0]
+	}
+
+	for {
+		var entry Entry[K, T]
+
+		err := dec.Decode(&entry)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			if replaced && len(batch) > 0 {
+				add() // the previous states are gone; keep the decoded ones
+			}
+
+			return cr.n, err
+		}
+
+		if batch = append(batch, entry); len(batch) == readBatch {
+			add()
+		}
+	}
+
+	if len(batch) > 0 || !replaced {
+		add()
+	}
+
+	return cr.n, nil
+}
+
+// countingWriter counts bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+
+	return n, err
+}
+
+// countingReader counts bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+
+	return n, err
+}
+
+var (
+	_ io.WriterTo   = (*Monitor[string, int])(nil)
+	_ io.ReaderFrom = (*Monitor[string, int])(nil)
+)
@@ -0,0 +1,57 @@
+package timestate
+
+import "container/heap"
+
+// Stale reports whether the state passed its deadline and is in the grace
+// period set by WithGracePeriod. A Watch that extends the deadline makes it
+// fresh again.
+func (m *Monitor[K, T]) Stale(key K) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	it, ok := m.items[key]
+
+	return ok && it.stale
+}
+
+// graceful moves items reaching their first deadline into the grace period
+// and returns the rest, which expire now, keeping their order.
+// Must be called with the lock held.
+func (m *Monitor[K, T]) graceful(expired []*item[K, T]) []*item[K, T] {
+	if m.grace <= 0 {
+		return expired
+	}
+
+	due := expired[:0]
+
+	for _, it := range expired {
+		if it.stale {
+			due = append(due, it)
+
+			continue
+		}
+
+		if m.staleCh != nil {
+			select {
+			case m.staleCh <- it.Key:
+			default:
+				heap.Push(&m.heap, it) // retry on the next check
+				m.backlog = false      // wait for the channel
+
+				continue
+			}
+		}
+
+		it.stale = true
+		it.Expires = it.Expires.Add(m.grace)
+		heap.Push(&m.heap, it)
+		m.persist(it)
+		m.rearm(it)
+
+		if m.maxSize > 0 {
+			heap.Fix(&m.byPriority, it.pindex)
+		}
+	}
+
+	return due
+}
@@ -0,0 +1,264 @@
+package timestate
+
+import "time"
+
+// WatchInGroup adds or updates a state like Watch and assigns it to the
+// named group, so all group members can be refreshed or removed together.
+// A key belongs to one group at a time; the last assignment wins.
+func (m *Monitor[K, T]) WatchInGroup(group string, key K, value T) bool {
+	now := m.clock.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.watchInGroup(group, key, value, m.defaultTTL, now)
+}
+
+// watchInGroup stores the value with the ttl and assigns the key to the
+// group. Must be called with the lock held.
+func (m *Monitor[K, T]) watchInGroup(group string, key K, value T, ttl time.Duration, now time.Time) bool {
+	updated := m.watch(key, value, ttl, m.expiresAt(now, ttl), false)
+
+	it, tracked := m.items[key]
+	if !tracked {
+		return false // rejected by the size limit
+	}
+
+	if it.group != group {
+		m.ungroup(it)
+		m.join(it, group)
+	}
+
+	return updated
+}
+
+// RemoveGroup removes all states of the group without expiration
+// notifications and returns their number.
+func (m *Monitor[K, T]) RemoveGroup(group string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var count int
+
+	for key := range m.groups[group] {
+		m.remove(m.items[key])
+		count++
+	}
+
+	return count
+}
+
+// TouchGroup resets the TTL of all states of the group without changing
+// their values and returns their number. States added with WatchUntil
+// keep their absolute deadline and, like pinned states, are not counted.
+func (m *Monitor[K, T]) TouchGroup(group string) int {
+	now := m.clock.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var count int
+
+	for key := range m.groups[group] {
+		if it := m.items[key]; !it.absolute && !it.pinned {
+			m.reschedule(it, m.expiresAt(now, it.ttl))
+			count++
+		}
+	}
+
+	return count
+}
+
+// join adds the item to the group index. Must be called with the lock held.
+func (m *Monitor[K, T]) join(it *item[K, T], group string) {
+	it.group = group
+	if group == "" {
+		return
+	}
+
+	if m.groups == nil {
+		m.groups = make(map[string]map[K]struct{})
+	}
+
+	members := m.groups[group]
+	if members == nil {
+		members = make(map[K]struct{})
+		m.groups[group] = members
+	}
+
+	members[it.Key] = struct{}{}
+}
+
+// ungroup removes the item from its group index, dropping empty groups.
+// Must be called with the lock held.
+func (m *Monitor[K, T]) ungroup(it *item[K, T]) {
+	if it.group == "" {
+		return
+	}
+
+	members := m.groups[it.group]
+	delete(members, it.Key)
+
+	if len(members) == 0 {
+		delete(m.groups, it.group)
+	}
+
+	it.group = ""
+}
+
+// GroupView scopes a monitor to one named group, so different kinds of
+// entities can share a single heap and checker. States watched through
+// the view join the group; lookups and removals ignore keys of other
+// groups. Keys are still unique across the whole monitor.
+type GroupView[K comparable, T any] struct {
+	m    *Monitor[K, T]
+	name string
+}
+
+// Group returns a view of the named group. Views with the same name share
+// the members and the default TTL. Returns nil for the empty name, which
+// stands for states outside any group.
+func (m *Monitor[K, T]) Group(name string) *GroupView[K, T] {
+	if name == "" {
+		return nil
+	}
+
+	return &GroupView[K, T]{m: m, name: name}
+}
+
+// Name returns the group name.
+func (g *GroupView[K, T]) Name() string {
+	return g.name
+}
+
+// SetDefaultTTL sets the TTL used by Watch of this group. Zero or negative
+// value restores the monitor default. Existing states keep their deadlines.
+func (g *GroupView[K, T]) SetDefaultTTL(ttl time.Duration) {
+	g.m.mu.Lock()
+	defer g.m.mu.Unlock()
+
+	if ttl <= 0 {
+		delete(g.m.groupTTLs, g.name)
+
+		return
+	}
+
+	if g.m.groupTTLs == nil {
+		g.m.groupTTLs = make(map[string]time.Duration)
+	}
+
+	g.m.groupTTLs[g.name] = ttl
+}
+
+// DefaultTTL returns the TTL used by Watch of this group.
+func (g *GroupView[K, T]) DefaultTTL() time.Duration {
+	g.m.mu.RLock()
+	defer g.m.mu.RUnlock()
+
+	return g.ttl()
+}
+
+// ttl returns the group default TTL. Must be called with the lock held.
+func (g *GroupView[K, T]) ttl() time.Duration {
+	if ttl, ok := g.m.groupTTLs[g.name]; ok {
+		return ttl
+	}
+
+	return g.m.defaultTTL
+}
+
+// Watch adds or updates a state with the group default TTL and moves it
+// to the group. Returns true if the state was added or its value changed.
+func (g *GroupView[K, T]) Watch(key K, value T) bool {
+	now := g.m.clock.Now()
+
+	g.m.mu.Lock()
+	defer g.m.mu.Unlock()
+
+	return g.m.watchInGroup(g.name, key, value, g.ttl(), now)
+}
+
+// WatchWithTTL is like Watch with a custom TTL.
+func (g *GroupView[K, T]) WatchWithTTL(key K, value T, ttl time.Duration) bool {
+	now := g.m.clock.Now()
+
+	g.m.mu.Lock()
+	defer g.m.mu.Unlock()
+
+	return g.m.watchInGroup(g.name, key, value, ttl, now)
+}
+
+// Get retrieves the value and deadline of a group member.
+func (g *GroupView[K, T]) Get(key K) (value T, expires time.Time, exists bool) {
+	g.m.mu.RLock()
+	defer g.m.mu.RUnlock()
+
+	if it, ok := g.m.items[key]; ok && it.group == g.name {
+		return it.Value, it.Expires, true
+	}
+
+	return value, time.Time{}, false
+}
+
+// Has reports whether the key is a member of the group.
+func (g *GroupView[K, T]) Has(key K) bool {
+	g.m.mu.RLock()
+	defer g.m.mu.RUnlock()
+
+	it, ok := g.m.items[key]
+
+	return ok && it.group == g.name
+}
+
+// Remove removes a group member without expiration notification.
+// Returns false if the key is not tracked or belongs to another group.
+func (g *GroupView[K, T]) Remove(key K) bool {
+	g.m.mu.Lock()
+	defer g.m.mu.Unlock()
+
+	it, ok := g.m.items[key]
+	if !ok || it.group != g.name {
+		return false
+	}
+
+	g.m.remove(it)
+
+	return true
+}
+
+// Len returns the number of group members.
+func (g *GroupView[K, T]) Len() int {
+	g.m.mu.RLock()
+	defer g.m.mu.RUnlock()
+
+	return len(g.m.groups[g.name])
+}
+
+// Keys returns the keys of group members in no particular order.
+func (g *GroupView[K, T]) Keys() []K {
+	g.m.mu.RLock()
+	defer g.m.mu.RUnlock()
+
+	keys := make([]K, 0, len(g.m.groups[g.name]))
+	for key := range g.m.groups[g.name] {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// Clear removes all group members like RemoveGroup and returns their number.
+func (g *GroupView[K, T]) Clear() int {
+	return g.m.RemoveGroup(g.name)
+}
+
+// Touch resets the TTL of all group members like TouchGroup.
+func (g *GroupView[K, T]) Touch() int {
+	return g.m.TouchGroup(g.name)
+}
+
+// Subscribe is like Monitor.SubscribeEvents, but only receives
+// expirations of group members.
+func (g *GroupView[K, T]) Subscribe(buffer int) (<-chan Event[K, T], func()) {
+	return g.m.subscribeEvents(buffer, nil, g.name)
+}
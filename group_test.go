@@ -0,0 +1,127 @@
+package timestate_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mdigger/timestate"
+)
+
+func TestRemoveGroup(t *testing.T) {
+	expiredCh := make(chan string, 10)
+	monitor := timestate.New[string, int](10*time.Millisecond, time.Minute, expiredCh)
+	ctx := t.Context()
+	monitor.Start(ctx)
+
+	monitor.WatchInGroup("conn1", "stream1", 1)
+	monitor.WatchInGroup("conn1", "stream2", 1)
+	monitor.WatchInGroup("conn2", "stream3", 1)
+	monitor.Watch("other", 1)
+
+	if n := monitor.RemoveGroup("conn1"); n != 2 {
+		t.Errorf("Unexpected removed count: %d", n)
+	}
+
+	if monitor.Has("stream1") || monitor.Has("stream2") {
+		t.Error("Group members should be removed")
+	}
+
+	if !monitor.Has("stream3") || !monitor.Has("other") {
+		t.Error("Other states should stay")
+	}
+
+	if n := monitor.RemoveGroup("conn1"); n != 0 {
+		t.Errorf("Group should be empty: %d", n)
+	}
+
+	// natural expiration cleans up the group index
+	monitor.WatchInGroup("conn3", "expiring", 1)
+	monitor.WatchUntil("expiring", 2, time.Now())
+
+	select {
+	case <-expiredCh:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("State did not expire as expected")
+	}
+
+	if n := monitor.RemoveGroup("conn3"); n != 0 {
+		t.Errorf("Expired state left in group: %d", n)
+	}
+}
+
+func TestTouchGroup(t *testing.T) {
+	monitor := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+
+	monitor.WatchInGroup("conn", "stream1", 1)
+	monitor.WatchInGroup("conn", "stream2", 1)
+	monitor.Watch("other", 1)
+
+	_, before, _ := monitor.Get("stream1")
+	_, otherBefore, _ := monitor.Get("other")
+
+	time.Sleep(10 * time.Millisecond)
+
+	if n := monitor.TouchGroup("conn"); n != 2 {
+		t.Errorf("Unexpected touched count: %d", n)
+	}
+
+	if _, after, _ := monitor.Get("stream1"); !after.After(before) {
+		t.Error("Group member TTL should be refreshed")
+	}
+
+	if _, otherAfter, _ := monitor.Get("other"); !otherAfter.Equal(otherBefore) {
+		t.Error("Non-member TTL should not change")
+	}
+}
+
+func TestGroupView(t *testing.T) {
+	clock := timestate.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	monitor := timestate.New(time.Second, time.Minute, make(chan string, 10),
+		timestate.WithClock[string, int](clock),
+	)
+
+	users := monitor.Group("users")
+	users.SetDefaultTTL(time.Hour)
+	hosts := monitor.Group("hosts")
+
+	users.Watch("alice", 1)
+	hosts.Watch("web", 2)
+
+	if _, expires, ok := users.Get("alice"); !ok || !expires.Equal(clock.Now().Add(time.Hour)) {
+		t.Errorf("Group TTL should be used, got %v", expires)
+	}
+
+	if _, expires, _ := hosts.Get("web"); !expires.Equal(clock.Now().Add(time.Minute)) {
+		t.Errorf("Monitor TTL should be used, got %v", expires)
+	}
+
+	if users.Has("web") || users.Remove("web") {
+		t.Error("Group should not see keys of other groups")
+	}
+
+	if users.Len() != 1 || monitor.Len() != 2 {
+		t.Errorf("Unexpected sizes: group %d, monitor %d", users.Len(), monitor.Len())
+	}
+
+	events, cancel := hosts.Subscribe(10)
+	defer cancel()
+
+	clock.Advance(2 * time.Minute)
+	monitor.Flush()
+
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 group event, got %d", len(events))
+	}
+
+	if event := <-events; event.Key != "web" {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+
+	if users.Clear() != 1 || monitor.Len() != 0 {
+		t.Error("Clear should remove the group members")
+	}
+
+	if monitor.Group("") != nil {
+		t.Error("The empty group name should be rejected")
+	}
+}
@@ -0,0 +1,28 @@
+package timestate
+
+// same reports whether the item holds value, comparing hashes when
+// WithHash is set. Must be called with the lock held.
+func (m *Monitor[K, T]) same(it *item[K, T], value T) bool {
+	if m.hash != nil {
+		return it.sum == m.hash(value)
+	}
+
+	return m.equal(it.Value, value)
+}
+
+// set stores the value of the item, or only its hash if values are
+// discarded. Must be called with the lock held.
+func (m *Monitor[K, T]) set(it *item[K, T], value T) {
+	if m.hash != nil {
+		it.sum = m.hash(value)
+	}
+
+	if m.discard {
+		var zero T
+		it.Value = zero
+
+		return
+	}
+
+	it.Value = value
+}
@@ -0,0 +1,36 @@
+package timestate
+
+// Hooks are callbacks for state lifecycle transitions, e.g. to maintain
+// secondary indexes keyed by value. They are called synchronously with the
+// monitor lock held, at the moment of the transition, so they always see
+// transitions in the order the monitor applies them. Hooks must be fast,
+// must not use the monitor and must not panic. Nil hooks are skipped.
+//
+// States loaded by Restore, ReadFrom or UnmarshalJSON are reported with
+// OnAdd, and the states they replace with OnRemove. Rename reports OnRemove
+// for the old key and then OnAdd for the new one.
+type Hooks[K comparable, T any] struct {
+	OnAdd    func(key K, value T)      // A new state is tracked
+	OnUpdate func(key K, old, value T) // The value of a tracked state changed
+	OnExpire func(key K, value T)      // The state expired
+	OnRemove func(key K, value T)      // The state was removed or evicted
+}
+
+// hookAdd calls the hook for a newly tracked item.
+// Must be called with the lock held.
+func (m *Monitor[K, T]) hookAdd(it *item[K, T]) {
+	if m.hooks.OnAdd != nil {
+		m.hooks.OnAdd(it.Key, it.Value)
+	}
+}
+
+// hookForget calls the hook for an item that is no longer tracked.
+// Must be called with the lock held.
+func (m *Monitor[K, T]) hookForget(it *item[K, T], reason Reason) {
+	switch {
+	case reason == ReasonExpired && m.hooks.OnExpire != nil:
+		m.hooks.OnExpire(it.Key, it.Value)
+	case reason != ReasonExpired && m.hooks.OnRemove != nil:
+		m.hooks.OnRemove(it.Key, it.Value)
+	}
+}
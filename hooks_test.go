@@ -0,0 +1,106 @@
+package timestate_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mdigger/timestate"
+)
+
+func TestHooks(t *testing.T) {
+	var log []string
+
+	record := func(name string) func(string, int) {
+		return func(key string, _ int) { log = append(log, name+":"+key) }
+	}
+
+	monitor := timestate.New(time.Second, time.Minute, make(chan string, 1),
+		timestate.WithHooks(timestate.Hooks[string, int]{
+			OnAdd:    record("add"),
+			OnUpdate: func(key string, _, _ int) { log = append(log, "update:"+key) },
+			OnExpire: record("expire"),
+			OnRemove: record("remove"),
+		}),
+	)
+
+	monitor.Watch("a", 1)
+	monitor.Watch("a", 1) // unchanged
+	monitor.Watch("a", 2)
+	monitor.Remove("a")
+	monitor.WatchWithTTL("b", 1, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	monitor.Flush()
+
+	want := []string{"add:a", "update:a", "remove:a", "add:b", "expire:b"}
+	if !slices.Equal(log, want) {
+		t.Errorf("Unexpected hook calls: %v, want %v", log, want)
+	}
+}
+
+func TestHooksIndex(t *testing.T) {
+	index := make(map[string]int) // maintained by hooks only
+
+	monitor := timestate.New(time.Second, time.Minute, make(chan string, 1),
+		timestate.WithHooks(timestate.Hooks[string, int]{
+			OnAdd:    func(key string, value int) { index[key] = value },
+			OnUpdate: func(key string, _, value int) { index[key] = value },
+			OnExpire: func(key string, _ int) { delete(index, key) },
+			OnRemove: func(key string, _ int) { delete(index, key) },
+		}),
+	)
+
+	check := func(step string) {
+		t.Helper()
+
+		want := make(map[string]int)
+		for _, entry := range monitor.Snapshot() {
+			want[entry.Key] = entry.Value
+		}
+
+		if !maps.Equal(index, want) {
+			t.Errorf("%s: index %v, want %v", step, index, want)
+		}
+	}
+
+	monitor.Watch("a", 1)
+	monitor.Rename("a", "b")
+	check("Rename")
+
+	monitor.Restore([]timestate.Entry[string, int]{
+		{Key: "c", Value: 3, Expires: time.Now().Add(time.Hour)},
+	})
+	check("Restore")
+
+	var buf bytes.Buffer
+
+	src := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+	src.Watch("c", 4)
+	src.Watch("d", 5)
+
+	if _, err := src.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := monitor.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	check("ReadFrom")
+
+	data, err := json.Marshal(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	monitor.Watch("e", 6)
+
+	if err := json.Unmarshal(data, monitor); err != nil {
+		t.Fatal(err)
+	}
+
+	check("UnmarshalJSON")
+}
@@ -0,0 +1,66 @@
+package timestate
+
+import "fmt"
+
+// checkInvariants verifies the internal consistency of the monitor:
+// the heap order, the positions stored in items, and that every tracked
+// state is reachable in the heap. Intended for tests.
+func (m *Monitor[K, T]) checkInvariants() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for i, it := range m.heap {
+		if it.index != i {
+			return fmt.Errorf("item %v: index %d at position %d", it.Key, it.index, i)
+		}
+
+		for _, child := range []int{2*i + 1, 2*i + 2} {
+			if child < len(m.heap) && m.heap.Less(child, i) {
+				return fmt.Errorf("item %v at %d expires before its parent %v",
+					m.heap[child].Key, child, it.Key)
+			}
+		}
+	}
+
+	var pinned int
+
+	for key, it := range m.items {
+		if it.Key != key {
+			return fmt.Errorf("item %v stored under key %v", it.Key, key)
+		}
+
+		if it.pinned {
+			pinned++
+
+			continue
+		}
+
+		if it.index < 0 || it.index >= len(m.heap) || m.heap[it.index] != it {
+			return fmt.Errorf("item %v is not reachable in the heap", key)
+		}
+	}
+
+	if len(m.heap)+pinned != len(m.items) {
+		return fmt.Errorf("heap: %d items, %d pinned, %d tracked", len(m.heap), pinned, len(m.items))
+	}
+
+	for i, it := range m.warnings {
+		if it.windex != i || it.warnAt.IsZero() || m.items[it.Key] != it {
+			return fmt.Errorf("item %v: warning index %d at position %d", it.Key, it.windex, i)
+		}
+	}
+
+	if m.maxSize > 0 {
+		if len(m.byPriority) != len(m.items) {
+			return fmt.Errorf("eviction heap: %d items, %d tracked", len(m.byPriority), len(m.items))
+		}
+
+		for i, it := range m.byPriority {
+			if it.pindex != i || m.items[it.Key] != it {
+				return fmt.Errorf("item %v: eviction index %d at position %d", it.Key, it.pindex, i)
+			}
+		}
+	}
+
+	return nil
+}
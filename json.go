@@ -0,0 +1,40 @@
+package timestate
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// MarshalJSON encodes all tracked states as a JSON array of
+// key/value/expires objects with RFC 3339 deadlines. Both K and T must be
+// JSON-serializable. States are ordered by deadline and then by the
+// formatted key, so equal monitors produce equal output.
+// Metadata attached with WatchWithMeta is not saved.
+func (m *Monitor[K, T]) MarshalJSON() ([]byte, error) {
+	list := m.Snapshot()
+	slices.SortFunc(list, func(a, b Entry[K, T]) int {
+		if c := a.Expires.Compare(b.Expires); c != 0 {
+			return c
+		}
+
+		return cmp.Compare(fmt.Sprint(a.Key), fmt.Sprint(b.Key))
+	})
+
+	return json.Marshal(list)
+}
+
+// UnmarshalJSON replaces all tracked states with ones decoded from data
+// produced by MarshalJSON. States already past their deadline are kept
+// and expire on the next check, so their notifications are not lost.
+func (m *Monitor[K, T]) UnmarshalJSON(data []byte) error {
+	var list []Entry[K, T]
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+
+	m.Restore(list)
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package timestate_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mdigger/timestate"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	src := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+	src.Watch("a", 1)
+	src.WatchUntil("b", 2, time.Now().Add(time.Hour))
+
+	data, err := json.Marshal(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expiredCh := make(chan string, 1)
+	dst := timestate.New[string, int](50*time.Millisecond, time.Minute, expiredCh)
+	if err := json.Unmarshal(data, dst); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"a", "b"} {
+		wantVal, wantExp, _ := src.Get(key)
+		val, exp, exists := dst.Get(key)
+		if !exists || val != wantVal || !exp.Equal(wantExp) {
+			t.Errorf("State %q mismatch: got %v %v, want %v %v", key, val, exp, wantVal, wantExp)
+		}
+	}
+}
+
+func TestJSONExpiredOnLoad(t *testing.T) {
+	expiredCh := make(chan string, 1)
+	monitor := timestate.New[string, int](50*time.Millisecond, time.Minute, expiredCh)
+	ctx := t.Context()
+	monitor.Start(ctx)
+
+	data := `[{"key":"old","value":1,"expires":"2020-01-01T00:00:00Z"}]`
+	if err := json.Unmarshal([]byte(data), monitor); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case key := <-expiredCh:
+		if key != "old" {
+			t.Errorf("Unexpected expired ID: %s", key)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Error("Past-due state did not expire after load")
+	}
+}
+
+func TestJSONStable(t *testing.T) {
+	deadline := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	monitor := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+	monitor.WatchUntil("b", 2, deadline)
+	monitor.WatchUntil("a", 1, deadline)
+	monitor.WatchUntil("c", 3, deadline.Add(-time.Hour))
+
+	data, err := json.Marshal(monitor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `[{"key":"c","value":3,"expires":"2029-12-31T23:00:00Z","absolute":true},` +
+		`{"key":"a","value":1,"expires":"2030-01-01T00:00:00Z","absolute":true},` +
+		`{"key":"b","value":2,"expires":"2030-01-01T00:00:00Z","absolute":true}]`
+	if string(data) != want {
+		t.Errorf("Unexpected JSON:\n%s\nwant:\n%s", data, want)
+	}
+}
@@ -0,0 +1,35 @@
+package timestate
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// logCheck logs the outcome of an expiration check: full channels and
+// timed out deliveries at Warn level, checks longer than the check
+// interval at Warn level, and a summary of other checks at Debug level.
+// Must be called without the lock.
+func (m *Monitor[K, T]) logCheck(expired int, full, timedOut uint64, elapsed, interval time.Duration) {
+	ctx := context.Background()
+
+	if full > 0 {
+		m.logger.LogAttrs(ctx, slog.LevelWarn, "timestate: expiration channel full",
+			slog.String("policy", m.overflow.String()), slog.Uint64("notifications", full))
+	}
+
+	if timedOut > 0 {
+		m.logger.LogAttrs(ctx, slog.LevelWarn, "timestate: expiration delivery timed out",
+			slog.Uint64("notifications", timedOut))
+	}
+
+	if interval > 0 && elapsed > interval {
+		m.logger.LogAttrs(ctx, slog.LevelWarn, "timestate: check took longer than the interval",
+			slog.Duration("elapsed", elapsed), slog.Duration("interval", interval), slog.Int("expired", expired))
+
+		return
+	}
+
+	m.logger.LogAttrs(ctx, slog.LevelDebug, "timestate: check",
+		slog.Duration("elapsed", elapsed), slog.Int("expired", expired))
+}
@@ -0,0 +1,30 @@
+package timestate
+
+import "unsafe"
+
+// mapEntryOverhead approximates per-entry map bookkeeping in bytes.
+const mapEntryOverhead = 8
+
+// ApproxMemory returns a rough estimate of the memory in bytes held by the
+// monitor for tracked states. It counts every tracked item, including
+// pinned ones, the backing arrays of the heaps by capacity, and map
+// entries (key, pointer and bookkeeping) by length. Memory referenced by
+// keys, values or metadata (strings, slices, pointers) is not included.
+func (m *Monitor[K, T]) ApproxMemory() int {
+	var (
+		key K
+		it  item[K, T]
+		ptr uintptr
+	)
+
+	itemSize := int(unsafe.Sizeof(it))
+	ptrSize := int(unsafe.Sizeof(ptr))
+	entrySize := int(unsafe.Sizeof(key)) + ptrSize + mapEntryOverhead
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.items)*itemSize +
+		(cap(m.heap)+cap(m.byPriority)+cap(m.warnings))*ptrSize +
+		len(m.items)*entrySize
+}
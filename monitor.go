@@ -3,160 +3,1699 @@ package timestate
 import (
 	"container/heap"
 	"context"
+	"errors"
+	"iter"
+	"log/slog"
+	"math/rand/v2"
+	"slices"
 	"sync"
 	"time"
 )
 
 // Monitor monitors states with TTL expiration and notifies via channel.
 // Uses min-heap for efficient expiration checks and map for O(1) state access.
-// Generic type T must be comparable for state change detection.
-type Monitor[K, T comparable] struct {
-	heap        items[K, T]       // Min-heap ordered by Expires
-	items       map[K]*item[K, T] // Key-value storage
-	mu          sync.Mutex        // Thread safety
-	defaultTTL  time.Duration     // Default state lifetime
-	checkTicker *time.Ticker      // Periodic checker
-	expiredCh   chan<- K          // Expiration notifications
+// Values are compared with == for change detection, or with the function
+// given to NewFunc.
+// Keys expired by a single check are delivered in non-decreasing deadline order.
+// Read-only methods such as Get, Has and Len share a read lock and do not
+// block each other; Get takes the write lock only with WithRefreshOnGet.
+type Monitor[K comparable, T any] struct {
+	heap          items[K, T]               // Min-heap ordered by Expires
+	items         map[K]*item[K, T]         // Key-value storage
+	mu            sync.RWMutex              // Thread safety
+	defaultTTL    time.Duration             // Default state lifetime
+	checkInterval time.Duration             // Initial check period
+	checkTicker   Ticker                    // Periodic checker
+	clock         Clock                     // Time source
+	expiredCh     chan<- K                  // Expiration notifications
+	stats         Stats                     // Cumulative counters
+	maxSize       int                       // Maximum tracked states (0 - unlimited)
+	evictedCh     chan<- K                  // Eviction notifications
+	onError       func(any)                 // Recovered panics handler
+	jitter        float64                   // Random TTL deviation fraction
+	refreshOnGet  bool                      // Get extends state lifetime
+	subscribers   map[chan K]struct{}       // Expiration fan-out
+	eventSubs     []*eventSub[K, T]         // Expiration event fan-out
+	stopped       bool                      // Background checker exited
+	cancel        context.CancelFunc        // Stops the background checker
+	done          chan struct{}             // Closed when the background checker exits
+	startDelay    time.Duration             // Delay before the first check
+	maxPerCheck   int                       // Expirations per check (0 - unlimited)
+	backlog       bool                      // Last check stopped at maxPerCheck
+	async         bool                      // Deliver from a dispatcher goroutine
+	outMu         sync.Mutex                // Guards the dispatcher queue
+	outbox        []*item[K, T]             // Notifications waiting for delivery
+	outWake       chan struct{}             // Signals queued notifications
+	dispatching   bool                      // Dispatcher is running
+	grace         time.Duration             // Extra window after the deadline
+	staleCh       chan<- K                  // Grace period notifications
+	compactRatio  float64                   // Unused heap capacity share triggering compaction
+	overflow      OverflowPolicy            // Full expiration channel handling
+	blockTimeout  time.Duration             // Maximum wait of blocking delivery
+	timing        *timing                   // Expiration lateness diagnostics
+	warnCh        chan<- K                  // Pre-expiration warnings
+	warnRatio     float64                   // Elapsed TTL share triggering warning
+	waiters       map[K][]chan error        // WaitFor subscribers
+	groups        map[string]map[K]struct{} // Group membership index
+	groupTTLs     map[string]time.Duration  // Default TTLs of GroupView
+	rounding      time.Duration             // Deadline rounding quantum
+	policy        ExpirationPolicy          // How updates affect deadlines
+	byPriority    priorityItems[K, T]       // Eviction order (with maxSize only)
+	warnings      warnItems[K, T]           // Pending warnings by time
+	debounce      time.Duration             // Repeated expiration suppression window
+	eviction      EvictionPolicy            // Which state makes room at the size limit
+	useSeq        uint64                    // Last use stamp for EvictLRU
+	lastExpired   map[K]time.Time           // Recent expirations for debounce
+	eventCh       chan<- Expired[K, T]      // Expiration events with values
+	expireFunc    func(K, T)                // Expiration callback
+	changesCh     chan<- Change[K, T]       // Value change notifications
+	eventsCh      chan<- Event[K, T]        // Unified lifecycle events
+	changelog     chan<- Record[K, T]       // Replication changelog
+	logSeq        uint64                    // Last changelog sequence number
+	logMu         sync.Mutex                // Guards the changelog queue
+	logQueue      []Record[K, T]            // Records waiting for shipLog
+	logSending    bool                      // shipLog is running
+	logPending    int                       // Changelog queue limit
+	logStop       chan struct{}             // Closed when the monitor stops
+	checkHook     func(CheckInfo)           // Completed check observer
+	logger        *slog.Logger              // Diagnostics logger
+	hooks         Hooks[K, T]               // Lifecycle callbacks
+	equal         func(a, b T) bool         // Value change detection
+	hash          func(T) uint64            // Replaces equal when set
+	discard       bool                      // Keep only value hashes
+	warnFunc      func(K, T)                // Pre-expiration warning callback
+	rearmCh       chan struct{}             // Earliest deadline changed (deadline timer only)
+	batchCh       chan<- []Expired[K, T]    // Batched expiration events
+	batchSize     int                       // Maximum batch length (0 - unlimited)
 }
 
+// Expired describes an expired state.
+type Expired[K comparable, T any] struct {
+	Key     K         // Expired key
+	Value   T         // Last value
+	Expires time.Time // Deadline the state expired at
+	Meta    any       // Metadata attached with WatchWithMeta
+}
+
+// ErrStopped is returned by mutating methods after the monitor was stopped
+// by canceling the context passed to Start or by Stop.
+var ErrStopped = errors.New("timestate: monitor stopped")
+
+// Defaults used by NewWithOptions.
+const (
+	defaultCheckInterval = time.Second
+	defaultStateTTL      = 5 * time.Minute
+)
+
 // New creates a Monitor instance.
 //
 // Parameters:
 //   - checkInterval: how often to check expirations (e.g., 1*time.Second)
 //   - defaultTTL: default state lifetime (e.g., 5*time.Minute)
-//   - expiredCh: buffered channel for expiration notifications (e.g., make(chan string, 100))
+//   - expiredCh: buffered channel for expiration notifications (e.g., make(chan string, 100));
+//     may be nil when expirations are handled only by callbacks or subscribers,
+//     but at least one notification mechanism should be configured
+//   - opts: optional settings (e.g., WithMaxSize[string, int](1000))
+//
+// It is a shorthand for NewWithOptions with WithCheckInterval, WithDefaultTTL
+// and WithExpiredChannel.
 func New[K, T comparable](
 	checkInterval time.Duration,
 	defaultTTL time.Duration,
 	expiredCh chan<- K,
+	opts ...Option[K, T],
+) *Monitor[K, T] {
+	return NewWithOptions(append([]Option[K, T]{
+		WithCheckInterval[K, T](checkInterval),
+		WithDefaultTTL[K, T](defaultTTL),
+		WithExpiredChannel[K, T](expiredCh),
+	}, opts...)...)
+}
+
+// NewWithOptions creates a Monitor configured only by options.
+// Without them it checks expirations every second, uses a 5 minute TTL and
+// has no expiration channel.
+func NewWithOptions[K, T comparable](opts ...Option[K, T]) *Monitor[K, T] {
+	return newMonitor(func(a, b T) bool { return a == b }, opts)
+}
+
+// NewFunc is like New for values that are not comparable with ==, such as
+// slices, maps or structs containing them. The equal function is used for
+// change detection, e.g. reflect.DeepEqual or slices.Equal.
+func NewFunc[K comparable, T any](
+	equal func(a, b T) bool,
+	checkInterval time.Duration,
+	defaultTTL time.Duration,
+	expiredCh chan<- K,
+	opts ...Option[K, T],
 ) *Monitor[K, T] {
-	return &Monitor[K, T]{
-		heap:        make(items[K, T], 0),
-		items:       make(map[K]*item[K, T]),
-		defaultTTL:  defaultTTL,
-		checkTicker: time.NewTicker(checkInterval),
-		expiredCh:   expiredCh,
+	return newMonitor(equal, append([]Option[K, T]{
+		WithCheckInterval[K, T](checkInterval),
+		WithDefaultTTL[K, T](defaultTTL),
+		WithExpiredChannel[K, T](expiredCh),
+	}, opts...))
+}
+
+// newMonitor creates a Monitor comparing values with equal.
+func newMonitor[K comparable, T any](equal func(a, b T) bool, opts []Option[K, T]) *Monitor[K, T] {
+	m := &Monitor[K, T]{
+		equal:         equal,
+		heap:          make(items[K, T], 0),
+		items:         make(map[K]*item[K, T]),
+		defaultTTL:    defaultStateTTL,
+		checkInterval: defaultCheckInterval,
+		compactRatio:  defaultCompactRatio,
+		clock:         systemClock{},
 	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if m.rearmCh == nil {
+		m.checkTicker = m.clock.NewTicker(m.checkInterval)
+	}
+
+	return m
 }
 
 // Watch adds or updates a state only if the value changed.
 // Uses defaultTTL for new states. Returns true if state was updated.
 func (m *Monitor[K, T]) Watch(key K, value T) bool {
-	return m.WatchWithTTL(key, value, m.defaultTTL)
+	now := m.clock.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.watch(key, value, m.defaultTTL, m.expiresAt(now, m.defaultTTL), false)
+}
+
+// WatchAll is like Watch for every entry of states, taking the lock once.
+// Returns the number of states added or changed.
+func (m *Monitor[K, T]) WatchAll(states map[K]T) int {
+	now := m.clock.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var count int
+
+	for key, value := range states {
+		if m.watch(key, value, m.defaultTTL, m.expiresAt(now, m.defaultTTL), false) {
+			count++
+		}
+	}
+
+	return count
+}
+
+// WatchResult is like Watch but also returns the value stored before the
+// call and whether the key was tracked, so transitions can be audited
+// without a racy Get before Watch.
+func (m *Monitor[K, T]) WatchResult(key K, value T) (old T, existed, changed bool) {
+	now := m.clock.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if it, exists := m.items[key]; exists {
+		old, existed = it.Value, true
+	}
+
+	changed = m.watch(key, value, m.defaultTTL, m.expiresAt(now, m.defaultTTL), false)
+
+	return old, existed, changed
+}
+
+// WatchIfAbsent adds a state with defaultTTL only if the key is not
+// tracked, like sync.Map.LoadOrStore. Returns the tracked value and true
+// if the key already existed, or value and false if it was added.
+// An existing state is left untouched, including its deadline. A new state
+// rejected by the RejectNew eviction policy is reported as added; use Has
+// to tell.
+func (m *Monitor[K, T]) WatchIfAbsent(key K, value T) (actual T, loaded bool) {
+	now := m.clock.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if it, exists := m.items[key]; exists {
+		return it.Value, true
+	}
+
+	m.insert(key, value, m.defaultTTL, m.expiresAt(now, m.defaultTTL), false)
+
+	return value, false
+}
+
+// TryWatch is like Watch but returns ErrStopped instead of tracking the
+// state when the monitor is stopped and nothing would ever expire it.
+func (m *Monitor[K, T]) TryWatch(key K, value T) (bool, error) {
+	now := m.clock.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stopped {
+		return false, ErrStopped
+	}
+
+	return m.watch(key, value, m.defaultTTL, m.expiresAt(now, m.defaultTTL), false), nil
 }
 
 // WatchWithTTL updates a state with custom TTL if the value changed.
 // Returns true if state was added/modified, false if unchanged.
+// Keys added with WatchUntil keep their absolute deadline.
 func (m *Monitor[K, T]) WatchWithTTL(key K, value T, ttl time.Duration) bool {
-	expires := time.Now().Add(ttl)
+	now := m.clock.Now()
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if it, exists := m.items[key]; exists {
-		if it.Value == value {
-			return false // unchanged
-		}
+	return m.watch(key, value, ttl, m.expiresAt(now, ttl), false)
+}
 
-		it.Value = value
-		it.Expires = expires
-		it.removed = false
+// WatchUntil adds or updates a state that expires at a fixed deadline.
+// Unlike sliding keys, later Watch or WatchWithTTL updates of the value
+// keep the original deadline instead of extending it.
+// Returns true if state was added/modified, false if unchanged.
+func (m *Monitor[K, T]) WatchUntil(key K, value T, deadline time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-		heap.Fix(&m.heap, 0) // reorder heap
+	return m.watch(key, value, 0, deadline, true)
+}
 
-		return true
+// ExpireAt stores the value and schedules its expiration at deadline,
+// replacing the deadline even if the value is unchanged (unlike
+// WatchUntil). Like WatchUntil, the deadline is absolute and not extended
+// by later updates. Returns true if the state was added or its value changed.
+func (m *Monitor[K, T]) ExpireAt(key K, value T, deadline time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	it, exists := m.items[key]
+	if !exists {
+		return m.insert(key, value, 0, deadline, true)
 	}
 
-	newItem := &item[K, T]{
-		Key:     key,
-		Value:   value,
-		Expires: expires,
+	changed := !m.same(it, value)
+	m.update(it, value, 0, deadline, true)
+
+	return changed
+}
+
+// Touch resets the TTL of a tracked state without changing its value,
+// using the TTL it was last watched with. Returns false if the state is
+// not tracked, pinned or was added with WatchUntil, whose deadline is fixed.
+func (m *Monitor[K, T]) Touch(key K) bool {
+	now := m.clock.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	it, exists := m.items[key]
+	if !exists || it.absolute || it.pinned {
+		return false
 	}
-	m.items[key] = newItem
-	heap.Push(&m.heap, newItem)
+
+	m.reschedule(it, m.expiresAt(now, it.ttl))
 
 	return true
 }
 
-// Get retrieves a state's value and expiration time.
-// Returns zero values if state doesn't exist or was removed.
-func (m *Monitor[K, T]) Get(key K) (value T, expires time.Time, exists bool) {
+// TouchTTL is like Touch but sets a new TTL for the state.
+func (m *Monitor[K, T]) TouchTTL(key K, ttl time.Duration) bool {
+	now := m.clock.Now()
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if it, ok := m.items[key]; ok && !it.removed {
-		return it.Value, it.Expires, true
+	it, exists := m.items[key]
+	if !exists || it.absolute || it.pinned {
+		return false
 	}
 
-	return value, time.Time{}, false
+	it.ttl = ttl
+	m.reschedule(it, m.expiresAt(now, ttl))
+
+	return true
 }
 
-// Remove removes a state without expiration notification.
-func (m *Monitor[K, T]) Remove(key K) {
+// SetTTL sets the remaining lifetime of a tracked state to ttl from now
+// without changing its value. Unlike TouchTTL, it also applies to states
+// with an absolute deadline, which stays absolute. Sliding states use ttl
+// for later refreshes. Returns false if the state is not tracked or pinned.
+func (m *Monitor[K, T]) SetTTL(key K, ttl time.Duration) bool {
+	now := m.clock.Now()
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if it, exists := m.items[key]; exists {
-		it.removed = true
+	it, exists := m.items[key]
+	if !exists || it.pinned {
+		return false
+	}
 
-		delete(m.items, key)
+	if !it.absolute {
+		it.ttl = ttl
 	}
+
+	m.reschedule(it, m.expiresAt(now, ttl))
+
+	return true
 }
 
-// Start begins monitoring in a background goroutine.
-// Stop by canceling the context.
-func (m *Monitor[K, T]) Start(ctx context.Context) {
-	go m.run(ctx)
+// Extend moves the deadline of a tracked state by delta, which may be
+// negative, without changing its value or the TTL used for refreshes.
+// Returns false if the state is not tracked or pinned.
+func (m *Monitor[K, T]) Extend(key K, delta time.Duration) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	it, exists := m.items[key]
+	if !exists || it.pinned {
+		return false
+	}
+
+	m.reschedule(it, it.Expires.Add(delta))
+
+	return true
 }
 
-func (m *Monitor[K, T]) run(ctx context.Context) {
-	for {
-		select {
-		case <-m.checkTicker.C:
-			m.checkExpirations()
-		case <-ctx.Done():
-			m.checkTicker.Stop()
+// WatchWithMeta adds or updates a state like Watch and attaches metadata to it.
+// Metadata is set once when the state is added and stays immutable for its
+// lifetime: it does not participate in change detection and is ignored for
+// already tracked keys.
+func (m *Monitor[K, T]) WatchWithMeta(key K, value T, meta any) bool {
+	now := m.clock.Now()
 
-			return
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, exists := m.items[key]
+	updated := m.watch(key, value, m.defaultTTL, m.expiresAt(now, m.defaultTTL), false)
+
+	if it, tracked := m.items[key]; tracked && !exists {
+		it.meta = meta
+	}
+
+	return updated
+}
+
+// WatchContext adds or updates a state like Watch and removes it without
+// expiration notification when ctx is done. No goroutine is kept per key:
+// the binding is released as soon as the state expires, is removed or is
+// bound to another context by a later WatchContext call.
+func (m *Monitor[K, T]) WatchContext(ctx context.Context, key K, value T) bool {
+	now := m.clock.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	updated := m.watch(key, value, m.defaultTTL, m.expiresAt(now, m.defaultTTL), false)
+
+	it, tracked := m.items[key]
+	if !tracked {
+		return false // rejected by the size limit
+	}
+
+	if it.stop != nil {
+		it.stop() // rebind to the new context
+	}
+
+	it.stop = context.AfterFunc(ctx, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		if m.items[it.Key] == it { // the key may have been renamed
+			m.remove(it)
 		}
+	})
+
+	return updated
+}
+
+// WatchWithCallback adds or updates a state like Watch and sets a callback
+// invoked when it expires, in addition to the channel notification.
+// A later WatchWithCallback replaces the callback; Remove and Rename drop it.
+// The callback runs in the background goroutine outside the lock; its
+// panics are recovered and reported to the WithErrorHandler handler.
+func (m *Monitor[K, T]) WatchWithCallback(key K, value T, onExpire func(K, T)) bool {
+	now := m.clock.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	updated := m.watch(key, value, m.defaultTTL, m.expiresAt(now, m.defaultTTL), false)
+
+	if it, tracked := m.items[key]; tracked {
+		it.onExpire = onExpire
 	}
+
+	return updated
 }
 
-func (m *Monitor[K, T]) checkExpirations() {
-	now := time.Now()
+// WatchIf adds or updates a state only if accept approves it. The predicate
+// receives the current value and whether the key is tracked, replacing the
+// default change detection: an accepted update always resets the TTL.
+// Returns true if the update was applied.
+func (m *Monitor[K, T]) WatchIf(key K, value T, accept func(old T, exists bool) bool) bool {
+	now := m.clock.Now()
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	for m.heap.Len() > 0 {
-		it := m.heap[0]
-		if it.Expires.After(now) {
-			break
+	var old T
+
+	it, exists := m.items[key]
+	if exists {
+		old = it.Value
+	}
+
+	if !accept(old, exists) {
+		return false
+	}
+
+	expires := m.expiresAt(now, m.defaultTTL)
+	if !exists {
+		return m.insert(key, value, m.defaultTTL, expires, false)
+	}
+
+	m.update(it, value, m.defaultTTL, expires, false)
+
+	return true
+}
+
+// Update atomically replaces a state with the result of f applied to the
+// current value and whether the key is tracked, resetting its TTL
+// (except for keys added with WatchUntil). Returns true if the value
+// was added or changed. The function is called with the lock held and
+// must not use the monitor.
+func (m *Monitor[K, T]) Update(key K, f func(old T, exists bool) T) bool {
+	return m.Compute(key, func(old T, exists bool) (T, bool) {
+		return f(old, exists), true
+	})
+}
+
+// Compute is like Update, but f also decides whether the state is kept:
+// with keep=false a tracked state is removed without expiration
+// notification and an untracked one is not added. Returns true if the
+// state was added, changed or removed. The function is called with the
+// lock held and must not use the monitor.
+func (m *Monitor[K, T]) Compute(key K, f func(old T, exists bool) (value T, keep bool)) bool {
+	now := m.clock.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var old T
+
+	it, exists := m.items[key]
+	if exists {
+		old = it.Value
+	}
+
+	value, keep := f(old, exists)
+
+	switch {
+	case !keep:
+		if exists {
+			m.remove(it)
 		}
 
-		heap.Pop(&m.heap)
+		return exists
+	case exists:
+		changed := !m.same(it, value)
+		m.update(it, value, m.defaultTTL, m.expiresAt(now, m.defaultTTL), false)
+
+		return changed
+	default:
+		return m.insert(key, value, m.defaultTTL, m.expiresAt(now, m.defaultTTL), false)
+	}
+}
 
-		if !it.removed && m.items[it.Key] == it {
-			select {
-			case m.expiredCh <- it.Key:
-				delete(m.items, it.Key)
-			default:
-				heap.Push(&m.heap, it) // requeue if channel full
+// watch stores the value and schedules its expiration.
+// The ttl is remembered for refreshes of sliding states.
+// Must be called with the lock held.
+func (m *Monitor[K, T]) watch(key K, value T, ttl time.Duration, expires time.Time, absolute bool) bool {
+	if it, exists := m.items[key]; exists {
+		m.use(it)
+
+		if m.same(it, value) {
+			if m.policy == SlidingOnAnyWatch && !absolute && !it.absolute {
+				it.ttl = ttl
+				m.reschedule(it, expires) // still alive
 			}
+
+			return false // unchanged
+		}
+
+		m.update(it, value, ttl, expires, absolute)
+
+		return true
+	}
+
+	return m.insert(key, value, ttl, expires, absolute)
+}
+
+// update changes the value of a tracked item and reschedules it unless
+// its deadline is absolute.
+func (m *Monitor[K, T]) update(it *item[K, T], value T, ttl time.Duration, expires time.Time, absolute bool) {
+	if !m.same(it, value) {
+		m.stats.TotalChanged++
+		m.changed(Change[K, T]{Key: it.Key, Old: it.Value, New: value, Existed: true})
+		m.emit(Event[K, T]{Key: it.Key, Value: it.Value, Reason: ReasonReplaced})
+
+		if m.hooks.OnUpdate != nil {
+			m.hooks.OnUpdate(it.Key, it.Value, value)
+		}
+	}
+
+	m.set(it, value)
+
+	switch {
+	case absolute:
+		it.absolute = true
+		m.reschedule(it, expires)
+	case !it.absolute:
+		it.ttl = ttl
+		m.reschedule(it, expires)
+	default:
+		m.persist(it) // the deadline is kept
+	}
+}
+
+// insert starts tracking a new item, evicting another one if the size
+// limit is reached. Returns false if the item was rejected instead.
+func (m *Monitor[K, T]) insert(key K, value T, ttl time.Duration, expires time.Time, absolute bool) bool {
+	if m.maxSize > 0 && len(m.items) >= m.maxSize {
+		if m.eviction == RejectNew {
+			m.stats.TotalRejected++
+
+			return false
+		}
+
+		m.evict()
+	}
+
+	newItem := &item[K, T]{
+		Key:      key,
+		Expires:  expires,
+		ttl:      ttl,
+		absolute: absolute || m.policy == Absolute,
+	}
+	m.set(newItem, value)
+	m.armWarning(newItem)
+	m.items[key] = newItem
+	heap.Push(&m.heap, newItem)
+	m.rearm(newItem)
+
+	if m.maxSize > 0 {
+		m.use(newItem)
+		heap.Push(&m.byPriority, newItem)
+	}
+
+	m.stats.TotalWatched++
+	m.changed(Change[K, T]{Key: key, New: value})
+	m.persist(newItem)
+	m.hookAdd(newItem)
+
+	return true
+}
+
+// expiresAt returns the deadline for ttl, perturbed by the configured jitter
+// and then rounded up to the configured quantum.
+func (m *Monitor[K, T]) expiresAt(now time.Time, ttl time.Duration) time.Time {
+	if m.jitter > 0 {
+		ttl += time.Duration((rand.Float64()*2 - 1) * m.jitter * float64(ttl)) //nolint:gosec
+	}
+
+	expires := now.Add(ttl)
+
+	if m.rounding > 0 {
+		if rounded := expires.Truncate(m.rounding); !rounded.Equal(expires) {
+			expires = rounded.Add(m.rounding)
+		}
+	}
+
+	return expires
+}
+
+// reschedule changes the item deadline and restores the heap order.
+func (m *Monitor[K, T]) reschedule(it *item[K, T], expires time.Time) {
+	if it.pinned {
+		m.persist(it) // deadline changes are ignored
+
+		return
+	}
+
+	it.Expires = expires
+	it.stale = false
+	m.armWarning(it)
+	heap.Fix(&m.heap, it.index)
+	m.persist(it)
+	m.rearm(it)
+
+	if m.maxSize > 0 {
+		heap.Fix(&m.byPriority, it.pindex)
+	}
+}
+
+// SetDefaultTTL changes the lifetime used by Watch for subsequent calls.
+// Already tracked states keep their current deadlines.
+func (m *Monitor[K, T]) SetDefaultTTL(ttl time.Duration) {
+	m.mu.Lock()
+	m.defaultTTL = ttl
+	m.mu.Unlock()
+}
+
+// DefaultTTL returns the lifetime used by Watch.
+func (m *Monitor[K, T]) DefaultTTL() time.Duration {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.defaultTTL
+}
+
+// SetCheckInterval changes how often expirations are checked.
+// Safe to call while monitoring is running: the next check happens
+// after the new interval elapses. With WithDeadlineTimer, it changes
+// the retry period for undelivered notifications and warnings. Zero or
+// negative d is ignored.
+func (m *Monitor[K, T]) SetCheckInterval(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+
+	m.mu.Lock()
+	m.checkInterval = d
+	m.mu.Unlock()
+
+	if m.checkTicker != nil {
+		m.checkTicker.Reset(d)
+	}
+}
+
+// SetExpiredChannel redirects expiration notifications to ch starting
+// with the next delivery. Notifications already being sent by a running
+// check complete on the old channel.
+func (m *Monitor[K, T]) SetExpiredChannel(ch chan<- K) {
+	m.mu.Lock()
+	m.expiredCh = ch
+	m.mu.Unlock()
+}
+
+// Get retrieves a state's value and expiration time.
+// Returns zero values if state doesn't exist or was removed.
+// With WithRefreshOnGet, a successful Get also extends the state lifetime
+// (except for keys added with WatchUntil).
+func (m *Monitor[K, T]) Get(key K) (value T, expires time.Time, exists bool) {
+	if !m.refreshOnGet {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+
+		if it, ok := m.items[key]; ok {
+			return it.Value, it.Expires, true
+		}
+
+		return value, time.Time{}, false
+	}
+
+	now := m.clock.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if it, ok := m.items[key]; ok {
+		if !it.absolute {
+			m.reschedule(it, m.expiresAt(now, it.ttl))
 		}
+
+		return it.Value, it.Expires, true
+	}
+
+	return value, time.Time{}, false
+}
+
+// Has reports whether a state is tracked for the key.
+// It never extends the state lifetime.
+func (m *Monitor[K, T]) Has(key K) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, ok := m.items[key]
+
+	return ok
+}
+
+// Len returns the number of tracked states.
+func (m *Monitor[K, T]) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.items)
+}
+
+// Keys returns the keys of all tracked states in no particular order.
+func (m *Monitor[K, T]) Keys() []K {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := make([]K, 0, len(m.items))
+	for key := range m.items {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// GetAll returns values of the given keys that are currently tracked,
+// under a single lock. Missing keys are absent from the result.
+// Unlike Get, it never extends state lifetimes.
+func (m *Monitor[K, T]) GetAll(keys []K) map[K]T {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make(map[K]T, len(keys))
+	for _, key := range keys {
+		if it, ok := m.items[key]; ok {
+			result[key] = it.Value
+		}
+	}
+
+	return result
+}
+
+// TTL returns the remaining lifetime of a tracked state measured by the
+// monitor clock. It never extends the state lifetime. Overdue states not
+// yet delivered report zero.
+func (m *Monitor[K, T]) TTL(key K) (remaining time.Duration, exists bool) {
+	now := m.clock.Now()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	it, exists := m.items[key]
+	if !exists {
+		return 0, false
+	}
+
+	return max(it.Expires.Sub(now), 0), true
+}
+
+// RemainingTTLAll returns the remaining lifetime of every tracked state
+// under a single lock. Overdue states not yet delivered report zero.
+func (m *Monitor[K, T]) RemainingTTLAll() map[K]time.Duration {
+	now := m.clock.Now()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make(map[K]time.Duration, len(m.items))
+	for key, it := range m.items {
+		result[key] = max(it.Expires.Sub(now), 0)
+	}
+
+	return result
+}
+
+// PeekMin returns the tracked state closest to expiration without
+// removing it. Returns ok=false if no states are tracked.
+func (m *Monitor[K, T]) PeekMin() (key K, value T, expires time.Time, ok bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.heap) == 0 {
+		return key, value, expires, false
+	}
+
+	it := m.heap[0]
+
+	return it.Key, it.Value, it.Expires, true
+}
+
+// NextExpiration returns the key and deadline of the state that expires
+// first, e.g. to show when the next check is due or to drive an external
+// scheduler with PopExpired. Pinned states are not considered. Returns
+// ok=false if nothing is scheduled.
+func (m *Monitor[K, T]) NextExpiration() (key K, at time.Time, ok bool) {
+	key, _, at, ok = m.PeekMin()
+
+	return key, at, ok
+}
+
+// ExpiringWithin returns keys of states that expire before d from now,
+// including overdue ones not yet delivered, sorted by deadline.
+// The monitor is not modified.
+func (m *Monitor[K, T]) ExpiringWithin(d time.Duration) []K {
+	return m.ExpiringBefore(m.clock.Now().Add(d))
+}
+
+// ExpiringBefore returns keys of states with deadlines before t, including
+// overdue ones not yet delivered, sorted by deadline. Only the part of the
+// heap holding such states is visited. The monitor is not modified.
+func (m *Monitor[K, T]) ExpiringBefore(t time.Time) []K {
+	return m.ExpiringBetween(time.Time{}, t)
+}
+
+// ExpiringBetween returns keys of states with deadlines in [from, to),
+// sorted by deadline, e.g. to poll devices whose states are about to
+// lapse. The monitor is not modified.
+func (m *Monitor[K, T]) ExpiringBetween(from, to time.Time) []K {
+	m.mu.RLock()
+
+	var (
+		list  []*item[K, T]
+		stack []int
+	)
+
+	if len(m.heap) > 0 {
+		stack = append(stack, 0)
+	}
+
+	// children never expire before their parent, so whole subtrees past
+	// the window are skipped
+	for len(stack) > 0 {
+		i := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		it := m.heap[i]
+		if !it.Expires.Before(to) {
+			continue
+		}
+
+		if !it.Expires.Before(from) {
+			list = append(list, it)
+		}
+
+		for _, child := range []int{2*i + 1, 2*i + 2} {
+			if child < len(m.heap) {
+				stack = append(stack, child)
+			}
+		}
+	}
+
+	slices.SortFunc(list, func(a, b *item[K, T]) int {
+		return a.Expires.Compare(b.Expires)
+	})
+
+	keys := make([]K, len(list))
+	for i, it := range list {
+		keys[i] = it.Key
+	}
+
+	m.mu.RUnlock()
+
+	return keys
+}
+
+// GetMeta returns metadata attached to a state by WatchWithMeta.
+// Returns nil if state doesn't exist or has no metadata.
+func (m *Monitor[K, T]) GetMeta(key K) (meta any, exists bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if it, ok := m.items[key]; ok {
+		return it.meta, true
+	}
+
+	return nil, false
+}
+
+// All returns an iterator over live states in no particular order.
+// The read lock is held for the whole loop, including its body: other
+// goroutines may read concurrently, but calling Monitor methods from the
+// loop body may deadlock.
+func (m *Monitor[K, T]) All() iter.Seq2[K, T] {
+	return func(yield func(K, T) bool) {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+
+		for key, it := range m.items {
+			if !yield(key, it.Value) {
+				return
+			}
+		}
+	}
+}
+
+// Range calls fn for each live state with its value and deadline, in no
+// particular order, until fn returns false. Like All, it holds the read
+// lock while fn runs, so fn must not call other Monitor methods.
+func (m *Monitor[K, T]) Range(fn func(key K, value T, expires time.Time) bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for key, it := range m.items {
+		if !fn(key, it.Value, it.Expires) {
+			return
+		}
+	}
+}
+
+// Remove removes a state without expiration notification.
+// Returns false if the state was not tracked, e.g. already expired.
+func (m *Monitor[K, T]) Remove(key K) bool {
+	_, existed := m.GetAndRemove(key)
+
+	return existed
+}
+
+// RemoveAll removes states with the given keys without expiration
+// notifications, taking the lock once. Returns the number of removed states.
+func (m *Monitor[K, T]) RemoveAll(keys ...K) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var count int
+
+	for _, key := range keys {
+		if it, exists := m.items[key]; exists {
+			m.remove(it)
+			count++
+		}
+	}
+
+	return count
+}
+
+// GetAndRemove removes a state without expiration notification and returns
+// its last value. Since both happen under the lock, only one of concurrent
+// callers (or the expiration) claims the state.
+func (m *Monitor[K, T]) GetAndRemove(key K) (value T, existed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	it, existed := m.items[key]
+	if !existed {
+		return value, false
+	}
+
+	m.remove(it)
+
+	return it.Value, true
+}
+
+// CompareAndSwap replaces the value of a tracked state with newValue only
+// if it still holds old, refreshing it like Watch with defaultTTL.
+// Returns whether the state held old.
+func (m *Monitor[K, T]) CompareAndSwap(key K, old, newValue T) bool {
+	now := m.clock.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if it, exists := m.items[key]; !exists || !m.same(it, old) {
+		return false
+	}
+
+	m.watch(key, newValue, m.defaultTTL, m.expiresAt(now, m.defaultTTL), false)
+
+	return true
+}
+
+// CompareAndRemove removes a state without expiration notification only
+// if it still holds old. Returns whether it was removed.
+func (m *Monitor[K, T]) CompareAndRemove(key K, old T) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	it, exists := m.items[key]
+	if !exists || !m.same(it, old) {
+		return false
+	}
+
+	m.remove(it)
+
+	return true
+}
+
+// Rename moves a state to a new key, keeping its value and deadline.
+// Returns false if oldKey is not tracked or newKey already is.
+func (m *Monitor[K, T]) Rename(oldKey, newKey K) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	it, exists := m.items[oldKey]
+	if !exists {
+		return false
+	}
+
+	if _, taken := m.items[newKey]; taken {
+		return false
+	}
+
+	group := it.group
+	m.ungroup(it)
+	m.wake(oldKey, ErrNotFound)
+	delete(m.items, oldKey)
+	m.unpersist(oldKey)
+
+	if m.hooks.OnRemove != nil {
+		m.hooks.OnRemove(oldKey, it.Value)
+	}
+
+	it.Key = newKey // the heap node is updated in place
+	it.onExpire = nil
+	m.items[newKey] = it
+	m.join(it, group)
+	m.persist(it)
+	m.hookAdd(it)
+
+	return true
+}
+
+// Clear removes all states without expiration notifications and
+// releases the memory held for them.
+func (m *Monitor[K, T]) Clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, it := range m.items {
+		m.forget(it, ReasonRemoved)
+		m.stats.TotalRemoved++
+	}
+
+	m.heap = make(items[K, T], 0)
+	m.items = make(map[K]*item[K, T])
+	m.byPriority = nil
+	m.warnings = nil
+}
+
+// RemoveWhere removes all states matching pred without expiration
+// notifications and returns their number. The predicate is called with
+// the lock held and must not use the monitor.
+func (m *Monitor[K, T]) RemoveWhere(pred func(K, T) bool) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var count int
+
+	for key, it := range m.items {
+		if pred(key, it.Value) {
+			m.remove(it)
+			count++
+		}
+	}
+
+	return count
+}
+
+// ExpireWhere moves the deadline of all states matching pred to now and
+// returns their number. They are delivered through the expiration channel
+// by the next check like any other expired state, including requeueing
+// while the channel is full. Pinned states are skipped. The predicate is
+// called with the lock held and must not use the monitor.
+func (m *Monitor[K, T]) ExpireWhere(pred func(K, T) bool) int {
+	now := m.clock.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var count int
+
+	for key, it := range m.items {
+		if !it.pinned && pred(key, it.Value) {
+			m.reschedule(it, now)
+			count++
+		}
+	}
+
+	return count
+}
+
+// forget deletes the item from the storage, reports why and releases its
+// resources. Must be called with the lock held.
+func (m *Monitor[K, T]) forget(it *item[K, T], reason Reason) {
+	delete(m.items, it.Key)
+	m.unpersist(it.Key)
+	m.emit(Event[K, T]{Key: it.Key, Value: it.Value, Reason: reason})
+	m.hookForget(it, reason)
+	m.wake(it.Key, ErrNotFound)
+	m.ungroup(it)
+	m.disarm(it)
+
+	if m.maxSize > 0 && it.pindex >= 0 {
+		heap.Remove(&m.byPriority, it.pindex)
+	}
+
+	if it.stop != nil {
+		it.stop()
+		it.stop = nil
+	}
+}
+
+// remove deletes the item from the heap and the storage.
+// Must be called with the lock held.
+func (m *Monitor[K, T]) remove(it *item[K, T]) {
+	m.unschedule(it)
+	m.forget(it, ReasonRemoved)
+	m.stats.TotalRemoved++
+}
+
+// Start begins monitoring in a background goroutine.
+// Stop by canceling the context or with Stop.
+func (m *Monitor[K, T]) Start(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	m.mu.Lock()
+	m.cancel, m.done = cancel, done
+	m.mu.Unlock()
+
+	if !m.async {
+		go func() {
+			defer close(done)
+			m.run(ctx)
+		}()
+
+		return
+	}
+
+	m.outMu.Lock()
+	m.dispatching = true
+	m.outMu.Unlock()
+
+	dispatched := make(chan struct{})
+
+	go func() {
+		defer close(dispatched)
+		m.dispatch(ctx)
+	}()
+
+	go func() {
+		defer close(done)
+		m.run(ctx)
+		<-dispatched
+	}()
+}
+
+// Stop stops the background checker and waits until it exits, so no
+// notifications are sent after it returns. Notifications interrupted by
+// Stop with WithBlockingDelivery or WithAsyncDelivery are kept and
+// delivered by Shutdown. With the Requeue policy, expired states that did
+// not fit the channel stay tracked and may be delivered with Flush; the
+// drop policies discard them as usual. Safe to call more than once and
+// without Start.
+func (m *Monitor[K, T]) Stop() {
+	m.mu.Lock()
+	cancel, done := m.cancel, m.done
+	m.mu.Unlock()
+
+	if cancel == nil {
+		m.markStopped()
+
+		return
+	}
+
+	cancel()
+	<-done
+}
+
+// shutdownRetry is how often Shutdown retries delivery to a full channel.
+const shutdownRetry = 10 * time.Millisecond
+
+// Shutdown stops the background checker like Stop and then delivers all
+// already expired states, retrying while the channel is full, until none
+// is left or ctx is done. Returns the ctx error if some notifications were
+// not delivered.
+func (m *Monitor[K, T]) Shutdown(ctx context.Context) error {
+	m.Stop()
+
+	if err := m.flushOutbox(ctx); err != nil {
+		return err
+	}
+
+	for {
+		m.checkExpirations(ctx)
+
+		if !m.pending() {
+			return nil
+		}
+
+		retry := m.clock.NewTimer(shutdownRetry)
+
+		select {
+		case <-retry.C():
+		case <-ctx.Done():
+			retry.Stop()
+
+			return ctx.Err()
+		}
+	}
+}
+
+// pending reports whether some expired states are still tracked.
+func (m *Monitor[K, T]) pending() bool {
+	now := m.clock.Now()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.heap) > 0 && !m.heap[0].Expires.After(now)
+}
+
+func (m *Monitor[K, T]) run(ctx context.Context) {
+	if m.startDelay > 0 {
+		delay := m.clock.NewTimer(m.startDelay)
+
+		select {
+		case <-delay.C():
+			m.drain(ctx)
+		case <-ctx.Done():
+			delay.Stop()
+		}
+	}
+
+	if m.checkTicker == nil {
+		m.runDeadlines(ctx)
+		m.markStopped()
+
+		return
+	}
+
+	for {
+		select {
+		case <-m.checkTicker.C():
+			m.drain(ctx)
+		case <-ctx.Done():
+			m.checkTicker.Stop()
+			m.markStopped()
+
+			return
+		}
+	}
+}
+
+// markStopped marks the background checker as exited.
+func (m *Monitor[K, T]) markStopped() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.stopped && m.logStop != nil {
+		close(m.logStop) // releases shipLog
+	}
+
+	m.stopped = true
+}
+
+// Flush immediately delivers all expired states, like the background check,
+// and returns their number. It may be called whether or not Start was
+// called. With WithBlockingDelivery, it waits until all notifications are
+// received.
+func (m *Monitor[K, T]) Flush() int {
+	return m.drain(context.Background())
+}
+
+// drain runs checks until no states are left over by the
+// WithMaxExpirePerCheck limit, releasing the lock between them so
+// writers are not blocked by an expiration storm. Returns the number of
+// expired states.
+func (m *Monitor[K, T]) drain(ctx context.Context) int {
+	var total int
+
+	for {
+		total += m.checkExpirations(ctx)
+
+		m.mu.RLock()
+		backlog := m.backlog
+		m.mu.RUnlock()
+
+		if !backlog || ctx.Err() != nil {
+			return total
+		}
+	}
+}
+
+// PopExpired removes up to limit states expired by now (all of them if
+// limit is zero or negative) and returns them in non-decreasing deadline
+// order. It allows running an own scheduling loop without Start: expired
+// states returned here are not sent to channels, subscribers or callbacks,
+// but are counted as expired and release WaitFor.
+func (m *Monitor[K, T]) PopExpired(now time.Time, limit int) []Expired[K, T] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var popped []Expired[K, T]
+
+	for len(m.heap) > 0 && (limit <= 0 || len(popped) < limit) {
+		it := m.heap[0]
+		if it.Expires.After(now) {
+			break
+		}
+
+		heap.Pop(&m.heap)
+		popped = append(popped, it.expired())
+		m.wake(it.Key, nil)
+		m.stats.TotalExpired++
+
+		if it.repeat > 0 {
+			m.recur(it, now)
+		} else {
+			m.forget(it, ReasonExpired)
+		}
+	}
+
+	return popped
+}
+
+// checkExpirations delivers expired states and returns their number.
+func (m *Monitor[K, T]) checkExpirations(ctx context.Context) int {
+	now := m.clock.Now()
+
+	m.mu.Lock()
+	dropsBefore := m.stats.TotalDropped
+	warned := m.warn(now)
+	m.pruneDebounce(now)
+	fired, panics := m.expire(now)
+	m.compactIfNeeded()
+	ch := m.expiredCh
+	drops := m.stats.TotalDropped - dropsBefore
+	interval := m.checkInterval
+	m.mu.Unlock()
+
+	// blocking delivery and callbacks happen outside the lock
+	for _, entry := range warned {
+		if r := call(m.warnFunc, entry.Key, entry.Value); r != nil {
+			panics = append(panics, r)
+		}
+	}
+
+	dropped, batchPanics := m.sendBatches(ctx, fired)
+	panics = append(panics, batchPanics...)
+
+	if m.async && m.batchCh == nil && len(fired) > 0 && !m.enqueue(fired) {
+		m.flushOutbox(ctx) // no dispatcher; the rest is left for Shutdown
+	}
+
+	var unsent []*item[K, T]
+
+	for _, it := range fired {
+		var (
+			sent = true
+			r    any
+		)
+
+		switch {
+		case m.overflow != Block, m.async, m.batchCh != nil:
+		case m.eventCh != nil:
+			sent, r = send(ctx, m.eventCh, it.expired(), m.timeout())
+		case ch != nil:
+			sent, r = send(ctx, ch, it.Key, m.timeout())
+		}
+
+		switch {
+		case r != nil:
+			panics = append(panics, r)
+		case sent:
+		case ctx.Err() == nil:
+			dropped++ // timed out
+		default:
+			unsent = append(unsent, it) // stopped; kept for Shutdown
+		}
+
+		if m.expireFunc != nil {
+			if r := call(m.expireFunc, it.Key, it.Value); r != nil {
+				panics = append(panics, r)
+			}
+		}
+
+		if it.onExpire != nil {
+			if r := call(it.onExpire, it.Key, it.Value); r != nil {
+				panics = append(panics, r)
+			}
+		}
+	}
+
+	m.postpone(unsent)
+
+	elapsed := m.clock.Now().Sub(now)
+
+	m.mu.Lock()
+	m.stats.TotalDropped += dropped
+	m.stats.TotalChecks++
+	m.stats.CheckDuration += elapsed
+	m.mu.Unlock()
+
+	if m.logger != nil {
+		m.logCheck(len(fired), drops, dropped, elapsed, interval)
+	}
+
+	if m.checkHook != nil {
+		info := CheckInfo{Start: now, Duration: elapsed, Expired: len(fired)}
+		for _, it := range fired {
+			info.Lateness = append(info.Lateness, now.Sub(it.Expires))
+		}
+
+		if r := m.observe(info); r != nil {
+			panics = append(panics, r)
+		}
+	}
+
+	// report outside the lock so the handler may use the monitor
+	if m.onError != nil {
+		for _, r := range panics {
+			m.onError(r)
+		}
+	}
+
+	return len(fired)
+}
+
+// expire delivers notifications for all states expired by now in
+// non-decreasing deadline order and returns the expired items, which are
+// no longer tracked. With blocking delivery, their keys are sent after the
+// lock is released. Also returns values of recovered panics.
+// Must be called with the lock held.
+func (m *Monitor[K, T]) expire(now time.Time) (fired []*item[K, T], panics []any) {
+	var expired []*item[K, T]
+
+	m.backlog = false
+
+	for m.heap.Len() > 0 {
+		it := m.heap[0]
+		if it.Expires.After(now) {
+			break
+		}
+
+		if m.maxPerCheck > 0 && len(expired) >= m.maxPerCheck {
+			m.backlog = true // carried over to the next check
+
+			break
+		}
+
+		heap.Pop(&m.heap)
+		expired = append(expired, it)
+	}
+
+	// heap order already follows deadlines; sorting makes it a guarantee
+	slices.SortStableFunc(expired, func(a, b *item[K, T]) int {
+		return a.Expires.Compare(b.Expires)
+	})
+
+	expired = m.graceful(expired)
+	due := expired[:0]
+
+	for _, it := range expired {
+		if m.debounced(it.Key, now) {
+			m.wake(it.Key, nil)
+
+			if it.repeat > 0 {
+				m.recur(it, now) // skips this notification only
+			} else {
+				m.forget(it, ReasonExpired) // expired silently
+			}
+
+			continue
+		}
+
+		due = append(due, it)
+	}
+
+	for i, it := range due {
+		if m.overflow != Block {
+			sent := false
+
+			var r any
+			if m.overflow != DropOldest || len(due)-i <= m.room() {
+				sent, r = m.notify(it)
+			}
+
+			if r != nil {
+				// the state is dropped so one failure can't stop the others
+				m.forget(it, ReasonExpired)
+				panics = append(panics, r)
+
+				continue
+			}
+
+			if !sent && m.overflow == Requeue {
+				for _, rest := range due[i:] {
+					heap.Push(&m.heap, rest) // requeue if channel full
+				}
+
+				m.stats.TotalDropped += uint64(len(due) - i)
+				m.backlog = false // wait for the channel
+
+				return fired, panics // retry on the next check
+			}
+
+			if !sent {
+				m.wake(it.Key, nil)
+				m.stats.TotalDropped++
+
+				if it.repeat > 0 {
+					m.recur(it, now)
+				} else {
+					m.forget(it, ReasonExpired) // expired without notification
+				}
+
+				continue
+			}
+		}
+
+		m.broadcast(it) // while it still belongs to its group
+		m.wake(it.Key, nil)
+		m.stats.TotalExpired++
+
+		if m.debounce > 0 {
+			m.lastExpired[it.Key] = now
+		}
+
+		if m.timing != nil {
+			m.timing.record(now.Sub(it.Expires))
+		}
+
+		if it.repeat > 0 {
+			last := *it // delivered after the lock is released
+			fired = append(fired, &last)
+			m.recur(it, now)
+
+			continue
+		}
+
+		m.forget(it, ReasonExpired)
+		fired = append(fired, it)
+	}
+
+	return fired, panics
+}
+
+// call invokes an expiration callback, recovering and returning a panic.
+func call[K, T any](fn func(K, T), key K, value T) (recovered any) {
+	defer func() {
+		recovered = recover()
+	}()
+
+	fn(key, value)
+
+	return nil
+}
+
+// send delivers an expiration notification, blocking until it is received,
+// ctx is done or timeout fires. A panic during delivery is recovered and
+// returned.
+func send[V any](ctx context.Context, ch chan<- V, v V, timeout Timer) (sent bool, recovered any) {
+	defer func() {
+		recovered = recover()
+	}()
+
+	var expired <-chan time.Time // nil blocks forever
+	if timeout != nil {
+		defer timeout.Stop()
+
+		expired = timeout.C()
+	}
+
+	select {
+	case ch <- v:
+		return true, nil
+	case <-ctx.Done():
+		return false, nil
+	case <-expired:
+		return false, nil
+	}
+}
+
+// timeout returns a timer limiting blocking delivery, or nil without
+// a limit.
+func (m *Monitor[K, T]) timeout() Timer {
+	if m.blockTimeout <= 0 {
+		return nil
+	}
+
+	return m.clock.NewTimer(m.blockTimeout)
+}
+
+// notify sends an expiration notification without blocking.
+// A panic during delivery (e.g. a send on a closed channel) is recovered
+// and returned.
+func (m *Monitor[K, T]) notify(it *item[K, T]) (sent bool, recovered any) {
+	defer func() {
+		recovered = recover()
+	}()
+
+	if m.batchCh != nil || m.async {
+		return true, nil // sent after the check
+	}
+
+	if m.eventCh != nil {
+		select {
+		case m.eventCh <- it.expired():
+			return true, nil
+		default:
+			return false, nil
+		}
+	}
+
+	if m.expiredCh == nil {
+		return true, nil // notified by other means only
+	}
+
+	select {
+	case m.expiredCh <- it.Key:
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// expired returns the expiration event for the item.
+func (it *item[K, T]) expired() Expired[K, T] {
+	return Expired[K, T]{
+		Key:     it.Key,
+		Value:   it.Value,
+		Expires: it.Expires,
+		Meta:    it.meta,
 	}
 }
 
 // item represents a single tracked entity with expiration.
-type item[K, T comparable] struct {
-	Key     K         // Unique identifier for the item
-	Value   T         // Current state value
-	Expires time.Time // Expiration timestamp
-	removed bool      // Soft-delete flag
+type item[K comparable, T any] struct {
+	Key      K             // Unique identifier for the item
+	Value    T             // Current state value
+	Expires  time.Time     // Expiration timestamp
+	ttl      time.Duration // Lifetime used for refreshes
+	index    int           // Position in the heap
+	absolute bool          // Deadline is not extended by updates
+	pinned   bool          // Excluded from expiration and the heap
+	meta     any           // Immutable metadata
+	stop     func() bool   // Releases the context binding
+	warnAt   time.Time     // Pending warning time (zero - none)
+	windex   int           // Position in the warning heap
+	group    string        // Group name (empty - none)
+	onExpire func(K, T)    // Per-key expiration callback
+	priority int           // Eviction priority (lower evicted first)
+	pindex   int           // Position in the eviction heap
+	used     uint64        // Last watch stamp (EvictLRU only)
+	sum      uint64        // Value hash (WithHash only)
+	repeat   time.Duration // WatchRepeating interval (0 - once)
+	stale    bool          // In the grace period after the deadline
 }
 
 // items is a min-heap of items ordered by expiration time.
-type items[K, T comparable] []*item[K, T]
+type items[K comparable, T any] []*item[K, T]
 
 func (h items[K, T]) Len() int { return len(h) }
 func (h items[K, T]) Less(i, j int) bool {
@@ -165,16 +1704,22 @@ func (h items[K, T]) Less(i, j int) bool {
 
 func (h items[K, T]) Swap(i, j int) {
 	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
 }
 
 func (h *items[K, T]) Push(x any) {
-	*h = append(*h, x.(*item[K, T])) //nolint:forcetypeassert
+	item := x.(*item[K, T]) //nolint:forcetypeassert
+	item.index = len(*h)
+	*h = append(*h, item)
 }
 
 func (h *items[K, T]) Pop() any {
 	old := *h
 	n := len(old)
 	item := old[n-1]
+	old[n-1] = nil // avoid memory leak
+	item.index = -1
 	*h = old[0 : n-1]
 
 	return item
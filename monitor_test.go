@@ -1,6 +1,15 @@
 package timestate_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -38,6 +47,8 @@ func TestBasicOperations(t *testing.T) {
 	if _, _, exists := monitor.Get("server1"); exists {
 		t.Error("State should be removed")
 	}
+
+	checkInvariants(t, monitor)
 }
 
 func TestExpiration(t *testing.T) {
@@ -76,3 +87,1272 @@ func TestCustomIDType(t *testing.T) {
 		t.Error("Failed with custom ID type")
 	}
 }
+
+func TestStats(t *testing.T) {
+	expiredCh := make(chan string, 10)
+	monitor := timestate.New[string, int](50*time.Millisecond, time.Minute, expiredCh)
+	ctx := t.Context()
+	monitor.Start(ctx)
+
+	monitor.Watch("a", 1)
+	monitor.Watch("a", 2) // update is not an insert
+	monitor.Watch("b", 1)
+	monitor.WatchWithTTL("c", 1, 10*time.Millisecond)
+	monitor.Remove("b")
+
+	select {
+	case <-expiredCh:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("State did not expire as expected")
+	}
+
+	stats := monitor.Stats()
+	if stats.Live != 1 || stats.TotalWatched != 3 || stats.TotalChanged != 1 ||
+		stats.TotalExpired != 1 || stats.TotalRemoved != 1 || stats.HeapSize != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+// publishRuns makes expvar names unique across repeated test runs.
+var publishRuns atomic.Int64
+
+func TestPublishStats(t *testing.T) {
+	monitor := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+	monitor.Watch("a", 1)
+
+	name := fmt.Sprintf("%s_%d", t.Name(), publishRuns.Add(1))
+	monitor.PublishStats(name)
+
+	var stats timestate.Stats
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &stats); err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.Live != 1 || stats.TotalWatched != 1 {
+		t.Errorf("Unexpected published stats: %+v", stats)
+	}
+}
+
+func TestSlidingExpiration(t *testing.T) {
+	monitor := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+
+	monitor.WatchWithTTL("key", 1, time.Second)
+	_, first, _ := monitor.Get("key")
+
+	time.Sleep(10 * time.Millisecond)
+	monitor.WatchWithTTL("key", 2, time.Second)
+
+	if _, expires, _ := monitor.Get("key"); !expires.After(first) {
+		t.Error("Update should extend sliding deadline")
+	}
+
+	checkInvariants(t, monitor)
+}
+
+func TestAbsoluteExpiration(t *testing.T) {
+	expiredCh := make(chan string, 1)
+	monitor := timestate.New[string, int](50*time.Millisecond, time.Minute, expiredCh)
+	ctx := t.Context()
+	monitor.Start(ctx)
+
+	deadline := time.Now().Add(200 * time.Millisecond)
+	if !monitor.WatchUntil("key", 1, deadline) {
+		t.Error("Expected true for new state")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if !monitor.Watch("key", 2) {
+		t.Error("Expected true for changed state")
+	}
+
+	if _, expires, _ := monitor.Get("key"); !expires.Equal(deadline) {
+		t.Errorf("Deadline changed: got %v, want %v", expires, deadline)
+	}
+
+	select {
+	case <-expiredCh:
+	case <-time.After(500 * time.Millisecond):
+		t.Error("State did not expire at its deadline")
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	monitor := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+
+	monitor.WatchWithMeta("key", 1, "10.0.0.1")
+
+	// Metadata is immutable and doesn't affect change detection
+	if monitor.WatchWithMeta("key", 1, "10.0.0.2") {
+		t.Error("Expected false for unchanged state")
+	}
+
+	if meta, exists := monitor.GetMeta("key"); !exists || meta != "10.0.0.1" {
+		t.Errorf("Unexpected metadata: %v", meta)
+	}
+
+	if _, exists := monitor.GetMeta("missing"); exists {
+		t.Error("Metadata of missing state should not exist")
+	}
+}
+
+func TestAll(t *testing.T) {
+	monitor := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+	monitor.Watch("a", 1)
+	monitor.Watch("b", 2)
+	monitor.Watch("c", 3)
+	monitor.Remove("c")
+
+	got := make(map[string]int)
+	for k, v := range monitor.All() {
+		got[k] = v
+	}
+
+	if len(got) != 2 || got["a"] != 1 || got["b"] != 2 {
+		t.Errorf("Unexpected states: %v", got)
+	}
+
+	count := 0
+	for range monitor.All() {
+		count++
+		break
+	}
+
+	if count != 1 {
+		t.Error("Iteration should stop on break")
+	}
+}
+
+func TestSetDefaultTTL(t *testing.T) {
+	monitor := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+	monitor.Watch("old", 1)
+
+	monitor.SetDefaultTTL(time.Hour)
+	if ttl := monitor.DefaultTTL(); ttl != time.Hour {
+		t.Errorf("Unexpected default TTL: %v", ttl)
+	}
+
+	monitor.Watch("new", 1)
+
+	_, oldExpires, _ := monitor.Get("old")
+	if d := time.Until(oldExpires); d > time.Minute {
+		t.Errorf("Existing state deadline changed: %v", d)
+	}
+
+	_, newExpires, _ := monitor.Get("new")
+	if d := time.Until(newExpires); d <= time.Minute || d > time.Hour {
+		t.Errorf("New state should use new TTL: %v", d)
+	}
+}
+
+func TestSetCheckInterval(t *testing.T) {
+	expiredCh := make(chan string, 1)
+	monitor := timestate.New[string, int](time.Hour, 10*time.Millisecond, expiredCh)
+	ctx := t.Context()
+	monitor.Start(ctx)
+
+	monitor.Watch("key", 1)
+	monitor.SetCheckInterval(20 * time.Millisecond)
+	monitor.SetCheckInterval(0)  // ignored
+	monitor.SetCheckInterval(-1) // ignored
+
+	select {
+	case <-expiredCh:
+	case <-time.After(500 * time.Millisecond):
+		t.Error("New check interval was not applied")
+	}
+}
+
+func TestTryWatchStopped(t *testing.T) {
+	monitor := timestate.New[string, int](10*time.Millisecond, time.Minute, make(chan string, 1))
+	ctx, cancel := context.WithCancel(t.Context())
+	monitor.Start(ctx)
+
+	if _, err := monitor.TryWatch("key", 1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	cancel()
+
+	deadline := time.Now().Add(500 * time.Millisecond)
+	for {
+		_, err := monitor.TryWatch("key", 2)
+		if errors.Is(err, timestate.ErrStopped) {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("Expected ErrStopped after cancel")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWatchIf(t *testing.T) {
+	monitor := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+
+	newer := func(value int) func(int, bool) bool {
+		return func(old int, exists bool) bool { return !exists || value > old }
+	}
+	changed := func(value int) func(int, bool) bool {
+		return func(old int, exists bool) bool { return !exists || value != old }
+	}
+	absent := func(_ int, exists bool) bool { return !exists }
+
+	// only if newer
+	if !monitor.WatchIf("seq", 5, newer(5)) || monitor.WatchIf("seq", 3, newer(3)) {
+		t.Error("Unexpected result for newer predicate")
+	}
+
+	if !monitor.WatchIf("seq", 7, newer(7)) {
+		t.Error("Expected newer value to be accepted")
+	}
+
+	// only if changed
+	if monitor.WatchIf("seq", 7, changed(7)) || !monitor.WatchIf("seq", 8, changed(8)) {
+		t.Error("Unexpected result for changed predicate")
+	}
+
+	// only if absent
+	if monitor.WatchIf("seq", 1, absent) || !monitor.WatchIf("other", 1, absent) {
+		t.Error("Unexpected result for absent predicate")
+	}
+
+	if val, _, _ := monitor.Get("seq"); val != 8 {
+		t.Errorf("Unexpected value: %d", val)
+	}
+}
+
+func TestGetAll(t *testing.T) {
+	monitor := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+	monitor.Watch("a", 1)
+	monitor.Watch("b", 2)
+	monitor.Watch("removed", 3)
+	monitor.Remove("removed")
+
+	got := monitor.GetAll([]string{"a", "b", "removed", "missing"})
+	if len(got) != 2 || got["a"] != 1 || got["b"] != 2 {
+		t.Errorf("Unexpected states: %v", got)
+	}
+}
+
+func TestRemoveWhere(t *testing.T) {
+	expiredCh := make(chan string, 10)
+	monitor := timestate.New[string, string](20*time.Millisecond, 50*time.Millisecond, expiredCh)
+	ctx := t.Context()
+	monitor.Start(ctx)
+
+	monitor.Watch("a", "shard1")
+	monitor.Watch("b", "shard2")
+	monitor.Watch("c", "shard1")
+
+	count := monitor.RemoveWhere(func(_ string, v string) bool { return v == "shard1" })
+	if count != 2 {
+		t.Errorf("Unexpected removed count: %d", count)
+	}
+
+	if _, _, exists := monitor.Get("b"); !exists {
+		t.Error("Non-matching state should stay")
+	}
+
+	// only the remaining state is notified
+	select {
+	case key := <-expiredCh:
+		if key != "b" {
+			t.Errorf("Unexpected expired ID: %s", key)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("State did not expire as expected")
+	}
+
+	select {
+	case key := <-expiredCh:
+		t.Errorf("Removed state notified: %s", key)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestExpireWhere(t *testing.T) {
+	expiredCh := make(chan string, 10)
+	monitor := timestate.New[string, string](20*time.Millisecond, time.Minute, expiredCh)
+	ctx := t.Context()
+	monitor.Start(ctx)
+
+	monitor.Watch("a", "bad")
+	monitor.Watch("b", "good")
+	monitor.Watch("c", "bad")
+
+	count := monitor.ExpireWhere(func(_ string, v string) bool { return v == "bad" })
+	if count != 2 {
+		t.Errorf("Unexpected expired count: %d", count)
+	}
+
+	got := make(map[string]bool)
+	for range 2 {
+		select {
+		case key := <-expiredCh:
+			got[key] = true
+		case <-time.After(500 * time.Millisecond):
+			t.Fatal("Matched state was not delivered")
+		}
+	}
+
+	if !got["a"] || !got["c"] {
+		t.Errorf("Unexpected expired keys: %v", got)
+	}
+
+	if _, _, exists := monitor.Get("b"); !exists {
+		t.Error("Non-matching state should stay")
+	}
+}
+
+func TestWatchContext(t *testing.T) {
+	monitor := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	monitor.WatchContext(ctx, "request", 1)
+
+	// rebinding to a new context releases the old one
+	ctx2, cancel2 := context.WithCancel(t.Context())
+	defer cancel2()
+	monitor.WatchContext(ctx2, "rebound", 1)
+	monitor.WatchContext(ctx, "rebound", 2)
+	monitor.WatchContext(ctx2, "rebound", 3)
+
+	cancel()
+
+	deadline := time.Now().Add(500 * time.Millisecond)
+	for monitor.Has("request") {
+		if time.Now().After(deadline) {
+			t.Fatal("State was not removed on context cancel")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	if !monitor.Has("rebound") {
+		t.Error("Rebound state should stay")
+	}
+}
+
+func TestWatchResult(t *testing.T) {
+	monitor := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+
+	tests := []struct {
+		value   int
+		old     int
+		existed bool
+		changed bool
+	}{
+		{value: 1, old: 0, existed: false, changed: true}, // new
+		{value: 2, old: 1, existed: true, changed: true},  // changed
+		{value: 2, old: 2, existed: true, changed: false}, // unchanged
+	}
+
+	for _, tt := range tests {
+		old, existed, changed := monitor.WatchResult("key", tt.value)
+		if old != tt.old || existed != tt.existed || changed != tt.changed {
+			t.Errorf("WatchResult(%d) = %d, %v, %v; want %d, %v, %v",
+				tt.value, old, existed, changed, tt.old, tt.existed, tt.changed)
+		}
+	}
+}
+
+func TestHas(t *testing.T) {
+	monitor := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+	monitor.Watch("present", 1)
+	monitor.Watch("removed", 1)
+	monitor.Remove("removed")
+
+	if !monitor.Has("present") {
+		t.Error("Expected present state")
+	}
+
+	if monitor.Has("removed") || monitor.Has("never") {
+		t.Error("Unexpected state")
+	}
+}
+
+func TestRemainingTTLAll(t *testing.T) {
+	monitor := timestate.New[string, int](time.Hour, time.Minute, make(chan string, 1))
+	monitor.WatchWithTTL("a", 1, time.Hour)
+	monitor.WatchUntil("overdue", 1, time.Now().Add(-time.Second))
+
+	got := monitor.RemainingTTLAll()
+	if len(got) != 2 || got["a"] <= time.Minute || got["a"] > time.Hour {
+		t.Errorf("Unexpected remaining TTLs: %v", got)
+	}
+
+	if got["overdue"] != 0 {
+		t.Errorf("Overdue TTL should be zero: %v", got["overdue"])
+	}
+}
+
+func benchmarkMonitor(b *testing.B, n int) (*timestate.Monitor[int, int], []int) {
+	b.Helper()
+
+	monitor := timestate.New[int, int](time.Hour, time.Hour, make(chan int, 1))
+	keys := make([]int, n)
+
+	for i := range n {
+		monitor.Watch(i, i)
+		keys[i] = i
+	}
+
+	return monitor, keys
+}
+
+func BenchmarkRemainingTTLAll(b *testing.B) {
+	monitor, _ := benchmarkMonitor(b, 1000)
+
+	for b.Loop() {
+		_ = monitor.RemainingTTLAll()
+	}
+}
+
+func BenchmarkGetParallel(b *testing.B) {
+	monitor, keys := benchmarkMonitor(b, 1000)
+
+	b.RunParallel(func(pb *testing.PB) {
+		var i int
+		for pb.Next() {
+			monitor.Get(keys[i%len(keys)])
+			i++
+		}
+	})
+}
+
+func BenchmarkRemainingTTLPerKey(b *testing.B) {
+	monitor, keys := benchmarkMonitor(b, 1000)
+
+	for b.Loop() {
+		result := make(map[int]time.Duration, len(keys))
+		for _, key := range keys {
+			if _, expires, ok := monitor.Get(key); ok {
+				result[key] = max(time.Until(expires), 0)
+			}
+		}
+	}
+}
+
+func TestExpirationOrder(t *testing.T) {
+	expiredCh := make(chan string, 10)
+	monitor := timestate.New[string, int](100*time.Millisecond, time.Minute, expiredCh)
+
+	keys := []string{"first", "second", "third", "fourth"}
+	for i := len(keys) - 1; i >= 0; i-- {
+		monitor.WatchWithTTL(keys[i], i, time.Duration(i+1)*10*time.Millisecond)
+	}
+
+	ctx := t.Context()
+	monitor.Start(ctx)
+
+	for _, want := range keys {
+		select {
+		case key := <-expiredCh:
+			if key != want {
+				t.Errorf("Unexpected order: got %s, want %s", key, want)
+			}
+		case <-time.After(500 * time.Millisecond):
+			t.Fatal("State did not expire as expected")
+		}
+	}
+}
+
+func TestUpdateConcurrent(t *testing.T) {
+	monitor := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+	increment := func(old int, _ bool) int { return old + 1 }
+
+	const count = 100
+
+	var wg sync.WaitGroup
+	for range count {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			monitor.Update("counter", increment)
+		}()
+	}
+
+	wg.Wait()
+
+	if val, _, _ := monitor.Get("counter"); val != count {
+		t.Errorf("Unexpected counter: got %d, want %d", val, count)
+	}
+}
+
+func TestCompute(t *testing.T) {
+	monitor := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+	decrement := func(old int, exists bool) (int, bool) {
+		if !exists {
+			return 2, true
+		}
+
+		return old - 1, old > 1
+	}
+
+	for want := 2; want > 0; want-- {
+		if !monitor.Compute("lease", decrement) {
+			t.Fatal("Compute reported no change")
+		}
+
+		if val, _, _ := monitor.Get("lease"); val != want {
+			t.Errorf("Unexpected value: got %d, want %d", val, want)
+		}
+	}
+
+	if !monitor.Compute("lease", decrement) || monitor.Has("lease") {
+		t.Error("State should be removed when not kept")
+	}
+
+	if monitor.Compute("missing", func(int, bool) (int, bool) { return 0, false }) {
+		t.Error("Nothing should change for a dropped new state")
+	}
+}
+
+func TestSetExpiredChannel(t *testing.T) {
+	oldCh := make(chan string, 10)
+	newCh := make(chan string, 10)
+	monitor := timestate.New[string, int](20*time.Millisecond, time.Minute, oldCh)
+	ctx := t.Context()
+	monitor.Start(ctx)
+
+	monitor.WatchWithTTL("before", 1, 10*time.Millisecond)
+
+	select {
+	case <-oldCh:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("State did not expire on the old channel")
+	}
+
+	monitor.SetExpiredChannel(newCh)
+	monitor.WatchWithTTL("after", 1, 10*time.Millisecond)
+
+	select {
+	case key := <-newCh:
+		if key != "after" {
+			t.Errorf("Unexpected expired ID: %s", key)
+		}
+	case <-oldCh:
+		t.Fatal("Expiration delivered to the old channel")
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("State did not expire on the new channel")
+	}
+}
+
+func TestPeekMin(t *testing.T) {
+	monitor := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+
+	if _, _, _, ok := monitor.PeekMin(); ok {
+		t.Error("Empty monitor should have no minimum")
+	}
+
+	monitor.WatchWithTTL("c", 3, 3*time.Minute)
+	monitor.WatchWithTTL("a", 1, time.Minute)
+	monitor.WatchWithTTL("b", 2, 2*time.Minute)
+
+	if key, val, _, ok := monitor.PeekMin(); !ok || key != "a" || val != 1 {
+		t.Errorf("Unexpected minimum: %s=%d", key, val)
+	}
+
+	monitor.Remove("a")
+
+	if key, _, _, ok := monitor.PeekMin(); !ok || key != "b" {
+		t.Errorf("Removed state should be skipped, got %s", key)
+	}
+
+	if !monitor.Has("b") || !monitor.Has("c") {
+		t.Error("PeekMin should not remove states")
+	}
+
+	checkInvariants(t, monitor)
+}
+
+func TestNextExpiration(t *testing.T) {
+	clock := timestate.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	monitor := timestate.New(time.Second, time.Minute, make(chan string, 1),
+		timestate.WithClock[string, int](clock),
+	)
+
+	if _, _, ok := monitor.NextExpiration(); ok {
+		t.Error("Empty monitor should have no next expiration")
+	}
+
+	monitor.WatchWithTTL("b", 2, 2*time.Minute)
+	monitor.WatchWithTTL("a", 1, time.Minute)
+	monitor.Pin("a")
+
+	if key, at, ok := monitor.NextExpiration(); !ok || key != "b" || !at.Equal(clock.Now().Add(2*time.Minute)) {
+		t.Errorf("Unexpected next expiration: %s at %v", key, at)
+	}
+}
+
+func TestExpiringWithin(t *testing.T) {
+	monitor := timestate.New[string, int](time.Hour, time.Minute, make(chan string, 1))
+	monitor.WatchWithTTL("late", 1, 2*time.Minute)
+	monitor.WatchWithTTL("soon", 1, time.Minute)
+	monitor.WatchWithTTL("outside", 1, time.Hour)
+	monitor.WatchUntil("overdue", 1, time.Now().Add(-time.Second))
+
+	got := monitor.ExpiringWithin(5 * time.Minute)
+	want := []string{"overdue", "soon", "late"}
+
+	if len(got) != len(want) {
+		t.Fatalf("Unexpected keys: %v", got)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Unexpected keys: got %v, want %v", got, want)
+
+			break
+		}
+	}
+
+	if !monitor.Has("overdue") {
+		t.Error("ExpiringWithin should not modify the monitor")
+	}
+}
+
+func TestExpiringBetween(t *testing.T) {
+	clock := timestate.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	monitor := timestate.New(time.Hour, time.Minute, make(chan int, 1),
+		timestate.WithClock[int, int](clock),
+	)
+
+	for _, i := range rand.Perm(100) {
+		monitor.WatchWithTTL(i, i, time.Duration(i+1)*time.Second)
+	}
+
+	start := clock.Now()
+
+	got := monitor.ExpiringBetween(start.Add(10*time.Second), start.Add(15*time.Second))
+	if !slices.Equal(got, []int{9, 10, 11, 12, 13}) {
+		t.Errorf("Unexpected keys: %v", got)
+	}
+
+	if got := monitor.ExpiringBefore(start.Add(3 * time.Second)); !slices.Equal(got, []int{0, 1}) {
+		t.Errorf("Unexpected keys: %v", got)
+	}
+}
+
+func TestRename(t *testing.T) {
+	expiredCh := make(chan string, 1)
+	monitor := timestate.New[string, int](10*time.Millisecond, time.Minute, expiredCh)
+	ctx := t.Context()
+	monitor.Start(ctx)
+
+	monitor.WatchWithTTL("temp", 1, 50*time.Millisecond)
+	monitor.Watch("taken", 2)
+	_, expires, _ := monitor.Get("temp")
+
+	if monitor.Rename("missing", "new") {
+		t.Error("Rename of missing key should fail")
+	}
+
+	if monitor.Rename("temp", "taken") {
+		t.Error("Rename to existing key should fail")
+	}
+
+	if !monitor.Rename("temp", "stable") {
+		t.Fatal("Rename failed")
+	}
+
+	if monitor.Has("temp") {
+		t.Error("Old key should not be tracked")
+	}
+
+	checkInvariants(t, monitor)
+
+	if val, exp, ok := monitor.Get("stable"); !ok || val != 1 || !exp.Equal(expires) {
+		t.Errorf("Renamed state mismatch: %d %v", val, exp)
+	}
+
+	select {
+	case key := <-expiredCh:
+		if key != "stable" {
+			t.Errorf("Unexpected expired ID: %s", key)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Error("Renamed state did not expire")
+	}
+}
+
+func checkInvariants[K, T comparable](t *testing.T, monitor *timestate.Monitor[K, T]) {
+	t.Helper()
+
+	if err := timestate.CheckInvariants(monitor); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHeapInvariants(t *testing.T) {
+	monitor := timestate.New[int, int](time.Second, time.Minute, make(chan int, 1))
+	rnd := rand.New(rand.NewPCG(1, 2))
+
+	for range 1000 {
+		key := rnd.IntN(50)
+
+		switch rnd.IntN(4) {
+		case 0:
+			monitor.Watch(key, rnd.IntN(5))
+		case 1:
+			monitor.WatchWithTTL(key, rnd.IntN(5), time.Duration(rnd.IntN(100))*time.Second)
+		case 2:
+			monitor.Remove(key)
+		case 3:
+			monitor.ExpireWhere(func(k, _ int) bool { return k == key })
+		}
+
+		checkInvariants(t, monitor)
+	}
+}
+
+func TestWatchWithCallback(t *testing.T) {
+	expiredCh := make(chan string, 10)
+	monitor := timestate.New[string, int](10*time.Millisecond, 30*time.Millisecond, expiredCh)
+	ctx := t.Context()
+	monitor.Start(ctx)
+
+	fired := make(chan string, 10)
+	onExpire := func(key string, value int) {
+		fired <- fmt.Sprintf("%s=%d", key, value)
+	}
+
+	monitor.WatchWithCallback("fire", 1, onExpire)
+	monitor.WatchWithCallback("removed", 1, onExpire)
+	monitor.Remove("removed")
+
+	// refresh keeps the callback and reports the last value
+	monitor.WatchWithCallback("refresh", 1, onExpire)
+	monitor.Watch("refresh", 2)
+
+	got := make(map[string]bool)
+	for range 2 {
+		select {
+		case event := <-fired:
+			got[event] = true
+		case <-time.After(500 * time.Millisecond):
+			t.Fatal("Callback was not called")
+		}
+	}
+
+	if !got["fire=1"] || !got["refresh=2"] {
+		t.Errorf("Unexpected callbacks: %v", got)
+	}
+
+	select {
+	case event := <-fired:
+		t.Errorf("Callback of removed state called: %s", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if len(expiredCh) != 2 {
+		t.Errorf("Channel notifications missing: %d", len(expiredCh))
+	}
+}
+
+func TestCallbackPanic(t *testing.T) {
+	errCh := make(chan any, 10)
+	expiredCh := make(chan string, 10)
+	monitor := timestate.New(10*time.Millisecond, time.Minute, expiredCh,
+		timestate.WithErrorHandler[string, int](func(r any) { errCh <- r }),
+	)
+	ctx := t.Context()
+	monitor.Start(ctx)
+
+	monitor.WatchWithCallback("panic", 1, func(string, int) { panic("boom") })
+	monitor.ExpireWhere(func(string, int) bool { return true })
+
+	select {
+	case r := <-errCh:
+		if r != "boom" {
+			t.Errorf("Unexpected panic value: %v", r)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Callback panic was not reported")
+	}
+
+	monitor.WatchWithTTL("next", 1, time.Millisecond)
+
+	select {
+	case <-expiredCh: // "panic" key
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("State did not expire as expected")
+	}
+
+	select {
+	case <-expiredCh: // "next" key
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Checker stopped after callback panic")
+	}
+}
+
+func TestNilExpiredChannel(t *testing.T) {
+	monitor := timestate.New[string, int](10*time.Millisecond, 10*time.Millisecond, nil)
+	ctx := t.Context()
+	monitor.Start(ctx)
+
+	fired := make(chan string, 1)
+	monitor.WatchWithCallback("key", 1, func(key string, _ int) { fired <- key })
+
+	select {
+	case <-fired:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Callback was not called with nil channel")
+	}
+
+	if monitor.Has("key") {
+		t.Error("Expired state should be removed")
+	}
+}
+
+func TestApproxMemory(t *testing.T) {
+	monitor := timestate.New[int, int](time.Second, time.Minute, make(chan int, 1))
+	empty := monitor.ApproxMemory()
+
+	last := empty
+	for i := range 100 {
+		monitor.Watch(i, i)
+
+		size := monitor.ApproxMemory()
+		if size <= last {
+			t.Fatalf("Memory estimate did not grow: %d -> %d", last, size)
+		}
+
+		last = size
+	}
+
+	monitor.Clear()
+
+	if size := monitor.ApproxMemory(); size >= last || monitor.Has(0) {
+		t.Errorf("Memory estimate did not shrink after Clear: %d", size)
+	}
+}
+
+func TestRemoveKeepsOrder(t *testing.T) {
+	monitor := timestate.New[int, int](time.Second, time.Minute, make(chan int, 1))
+
+	for i := range 100 {
+		monitor.WatchWithTTL(i, i, time.Duration(100-i)*time.Minute)
+	}
+
+	before := monitor.ApproxMemory()
+
+	for i := 0; i < 100; i += 2 {
+		monitor.Remove(i)
+		checkInvariants(t, monitor)
+	}
+
+	if size := monitor.ApproxMemory(); size >= before {
+		t.Errorf("Removed states still held: %d >= %d", size, before)
+	}
+
+	if key, _, _, ok := monitor.PeekMin(); !ok || key != 99 {
+		t.Errorf("Unexpected earliest state: %d", key)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	monitor := timestate.New[int, int](time.Second, time.Minute, make(chan int, 1),
+		timestate.WithCompactionThreshold[int, int](0), // only explicit compaction
+		timestate.WithMaxSize[int, int](1000),
+	)
+
+	for i := range 1000 {
+		monitor.Watch(i, i)
+	}
+
+	for i := range 990 {
+		monitor.Remove(i)
+	}
+
+	before := monitor.ApproxMemory()
+	monitor.Compact()
+
+	if size := monitor.ApproxMemory(); size >= before {
+		t.Errorf("Compact did not release memory: %d >= %d", size, before)
+	}
+
+	if monitor.Len() != 10 || !monitor.Has(999) {
+		t.Error("Compact lost states")
+	}
+
+	checkInvariants(t, monitor)
+}
+
+func TestStop(t *testing.T) {
+	monitor := timestate.New[string, int](time.Millisecond, time.Minute, make(chan string, 1))
+	monitor.Start(t.Context())
+	monitor.Stop()
+	monitor.Stop() // idempotent
+
+	if _, err := monitor.TryWatch("key", 1); !errors.Is(err, timestate.ErrStopped) {
+		t.Errorf("Expected ErrStopped, got %v", err)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	expiredCh := make(chan int, 1)
+	monitor := timestate.New[int, int](time.Hour, time.Nanosecond, expiredCh)
+	monitor.Start(t.Context())
+
+	for i := range 3 {
+		monitor.Watch(i, i)
+	}
+
+	received := make(chan int, 3)
+	go func() {
+		for key := range expiredCh {
+			time.Sleep(5 * time.Millisecond) // slow consumer
+			received <- key
+		}
+	}()
+
+	time.Sleep(time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+	defer cancel()
+
+	if err := monitor.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	if monitor.Len() != 0 {
+		t.Errorf("Expired states left after Shutdown: %d", monitor.Len())
+	}
+
+	close(expiredCh)
+
+	for range 3 {
+		<-received
+	}
+}
+
+func TestPopExpired(t *testing.T) {
+	monitor := timestate.New[int, int](time.Hour, time.Minute, nil)
+	now := time.Now()
+
+	for i := range 5 {
+		monitor.WatchUntil(i, i*10, now.Add(time.Duration(i-3)*time.Second))
+	}
+
+	expired := monitor.PopExpired(now, 2)
+	if len(expired) != 2 || expired[0].Key != 0 || expired[1].Value != 10 {
+		t.Fatalf("Unexpected first batch: %+v", expired)
+	}
+
+	expired = monitor.PopExpired(now, 0)
+	if len(expired) != 2 || expired[0].Key != 2 || expired[1].Key != 3 {
+		t.Fatalf("Unexpected second batch: %+v", expired)
+	}
+
+	if monitor.Len() != 1 || monitor.Stats().TotalExpired != 4 {
+		t.Errorf("Unexpected state after PopExpired: %+v", monitor.Stats())
+	}
+
+	checkInvariants(t, monitor)
+}
+
+func TestGetAndRemove(t *testing.T) {
+	monitor := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+	monitor.Watch("key", 42)
+
+	if value, existed := monitor.GetAndRemove("key"); !existed || value != 42 {
+		t.Errorf("Unexpected result: %d, %v", value, existed)
+	}
+
+	if _, existed := monitor.GetAndRemove("key"); existed {
+		t.Error("State claimed twice")
+	}
+
+	monitor.Watch("other", 1)
+
+	if !monitor.Remove("other") || monitor.Remove("other") {
+		t.Error("Remove should report whether the state existed")
+	}
+}
+
+func TestWatchIfAbsent(t *testing.T) {
+	monitor := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+
+	if actual, loaded := monitor.WatchIfAbsent("key", 1); loaded || actual != 1 {
+		t.Errorf("Unexpected result for new key: %d, %v", actual, loaded)
+	}
+
+	_, expires, _ := monitor.Get("key")
+
+	if actual, loaded := monitor.WatchIfAbsent("key", 2); !loaded || actual != 1 {
+		t.Errorf("Unexpected result for existing key: %d, %v", actual, loaded)
+	}
+
+	if value, exp, _ := monitor.Get("key"); value != 1 || !exp.Equal(expires) {
+		t.Error("Existing state was modified")
+	}
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	monitor := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+
+	if monitor.CompareAndSwap("key", 0, 1) {
+		t.Error("Untracked state swapped")
+	}
+
+	monitor.Watch("key", 1)
+
+	if monitor.CompareAndSwap("key", 2, 3) {
+		t.Error("State swapped with wrong old value")
+	}
+
+	if !monitor.CompareAndSwap("key", 1, 2) {
+		t.Error("State not swapped")
+	}
+
+	if monitor.CompareAndRemove("key", 1) {
+		t.Error("State removed with wrong old value")
+	}
+
+	if !monitor.CompareAndRemove("key", 2) || monitor.Has("key") {
+		t.Error("State not removed")
+	}
+}
+
+func TestWatchAllRemoveAll(t *testing.T) {
+	monitor := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+	monitor.Watch("a", 1)
+
+	if n := monitor.WatchAll(map[string]int{"a": 1, "b": 2, "c": 3}); n != 2 {
+		t.Errorf("Unexpected changed count: %d", n)
+	}
+
+	if n := monitor.RemoveAll("a", "b", "missing"); n != 2 {
+		t.Errorf("Unexpected removed count: %d", n)
+	}
+
+	if keys := monitor.Keys(); len(keys) != 1 || keys[0] != "c" {
+		t.Errorf("Unexpected keys: %v", keys)
+	}
+
+	checkInvariants(t, monitor)
+}
+
+func TestExpireAt(t *testing.T) {
+	monitor := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+	deadline := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	if !monitor.ExpireAt("key", 1, deadline) {
+		t.Error("New state not reported")
+	}
+
+	later := deadline.Add(time.Hour)
+	if monitor.ExpireAt("key", 1, later) {
+		t.Error("Unchanged value reported as changed")
+	}
+
+	if _, expires, _ := monitor.Get("key"); !expires.Equal(later) {
+		t.Errorf("Deadline not moved: %v", expires)
+	}
+
+	monitor.Watch("key", 2) // absolute deadline is kept
+
+	if _, expires, _ := monitor.Get("key"); !expires.Equal(later) {
+		t.Errorf("Deadline changed by Watch: %v", expires)
+	}
+}
+
+func TestSetTTLExtend(t *testing.T) {
+	clock := timestate.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	monitor := timestate.New(time.Second, time.Minute, make(chan string, 1),
+		timestate.WithClock[string, int](clock),
+	)
+	monitor.Watch("lease", 1)
+
+	if !monitor.SetTTL("lease", time.Hour) {
+		t.Fatal("SetTTL failed")
+	}
+
+	if !monitor.Extend("lease", -10*time.Minute) {
+		t.Fatal("Extend failed")
+	}
+
+	if _, expires, _ := monitor.Get("lease"); !expires.Equal(clock.Now().Add(50 * time.Minute)) {
+		t.Errorf("Unexpected deadline: %v", expires)
+	}
+
+	monitor.Touch("lease") // refreshes with the TTL set by SetTTL
+
+	if _, expires, _ := monitor.Get("lease"); !expires.Equal(clock.Now().Add(time.Hour)) {
+		t.Errorf("Unexpected deadline after Touch: %v", expires)
+	}
+
+	if monitor.SetTTL("missing", time.Hour) || monitor.Extend("missing", time.Hour) {
+		t.Error("Untracked state changed")
+	}
+
+	checkInvariants(t, monitor)
+}
+
+func TestFlush(t *testing.T) {
+	expiredCh := make(chan string, 1)
+	monitor := timestate.New[string, int](time.Hour, time.Minute, expiredCh)
+
+	monitor.WatchWithTTL("key", 1, time.Millisecond)
+	monitor.Watch("other", 1)
+	time.Sleep(5 * time.Millisecond)
+
+	if n := monitor.Flush(); n != 1 {
+		t.Errorf("Unexpected flushed count: %d", n)
+	}
+
+	select {
+	case key := <-expiredCh:
+		if key != "key" {
+			t.Errorf("Unexpected expired ID: %s", key)
+		}
+	default:
+		t.Error("Flushed state was not delivered")
+	}
+}
+
+func TestTouch(t *testing.T) {
+	monitor := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+	monitor.Watch("key", 1)
+	monitor.WatchUntil("fixed", 1, time.Now().Add(time.Hour))
+
+	_, before, _ := monitor.Get("key")
+	time.Sleep(10 * time.Millisecond)
+
+	if !monitor.Touch("key") {
+		t.Fatal("Touch failed")
+	}
+
+	if val, after, _ := monitor.Get("key"); val != 1 || !after.After(before) {
+		t.Error("Touch should extend TTL and keep value")
+	}
+
+	if !monitor.TouchTTL("key", time.Hour) {
+		t.Fatal("TouchTTL failed")
+	}
+
+	if _, expires, _ := monitor.Get("key"); time.Until(expires) <= time.Minute {
+		t.Error("TouchTTL should apply the new TTL")
+	}
+
+	if monitor.Touch("missing") || monitor.Touch("fixed") || monitor.TouchTTL("fixed", time.Hour) {
+		t.Error("Touch should fail for missing and absolute states")
+	}
+
+	checkInvariants(t, monitor)
+}
+
+func TestLenKeys(t *testing.T) {
+	monitor := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+	monitor.Watch("b", 1)
+	monitor.Watch("a", 1)
+	monitor.Watch("c", 1)
+	monitor.Remove("c")
+
+	if n := monitor.Len(); n != 2 {
+		t.Errorf("Unexpected length: %d", n)
+	}
+
+	keys := monitor.Keys()
+	slices.Sort(keys)
+
+	if !slices.Equal(keys, []string{"a", "b"}) {
+		t.Errorf("Unexpected keys: %v", keys)
+	}
+}
+
+func TestRange(t *testing.T) {
+	monitor := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+	monitor.Watch("a", 1)
+	monitor.Watch("b", 2)
+
+	got := make(map[string]int)
+	monitor.Range(func(key string, value int, expires time.Time) bool {
+		if expires.IsZero() {
+			t.Errorf("Missing deadline for %s", key)
+		}
+
+		got[key] = value
+
+		return true
+	})
+
+	if len(got) != 2 || got["a"] != 1 || got["b"] != 2 {
+		t.Errorf("Unexpected states: %v", got)
+	}
+
+	count := 0
+	monitor.Range(func(string, int, time.Time) bool {
+		count++
+
+		return false
+	})
+
+	if count != 1 {
+		t.Error("Range should stop when fn returns false")
+	}
+}
+
+func TestNewFunc(t *testing.T) {
+	expiredCh := make(chan string, 1)
+	monitor := timestate.NewFunc[string, []string](slices.Equal, time.Second, time.Minute, expiredCh)
+
+	if !monitor.Watch("tags", []string{"a", "b"}) {
+		t.Error("New state should be reported as updated")
+	}
+
+	if monitor.Watch("tags", []string{"a", "b"}) {
+		t.Error("Equal slice should not be reported as updated")
+	}
+
+	if !monitor.Watch("tags", []string{"a"}) {
+		t.Error("Changed slice should be reported as updated")
+	}
+
+	if value, _, ok := monitor.Get("tags"); !ok || !slices.Equal(value, []string{"a"}) {
+		t.Errorf("Unexpected value: %v", value)
+	}
+}
+
+func TestConcurrentReads(t *testing.T) {
+	monitor := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+	monitor.Watch("a", 1)
+
+	monitor.Range(func(string, int, time.Time) bool {
+		done := make(chan bool)
+		go func() {
+			_, _, ok := monitor.Get("a")
+			done <- ok
+		}()
+
+		select {
+		case ok := <-done:
+			if !ok {
+				t.Error("Get should find the state")
+			}
+		case <-time.After(time.Second):
+			t.Error("Get should not wait for Range")
+		}
+
+		return false
+	})
+}
+
+func TestReader(t *testing.T) {
+	monitor := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+	monitor.Watch("a", 1)
+
+	reader := monitor.Reader()
+
+	if value, _, exists := reader.Get("a"); !exists || value != 1 || reader.Len() != 1 {
+		t.Errorf("Unexpected state: %v, %v, %d", value, exists, reader.Len())
+	}
+
+	if snapshot := reader.Snapshot(); len(snapshot) != 1 || snapshot[0].Key != "a" {
+		t.Errorf("Unexpected snapshot: %+v", snapshot)
+	}
+}
@@ -0,0 +1,25 @@
+package timestate
+
+import "time"
+
+// CheckInfo describes a completed expiration check. It carries what is
+// needed to record metrics and trace spans, e.g. with OpenTelemetry,
+// without a dependency on a telemetry library.
+type CheckInfo struct {
+	Start    time.Time       // When the check started
+	Duration time.Duration   // Time spent, including delivery and callbacks
+	Expired  int             // Number of expired states
+	Lateness []time.Duration // Delay between each deadline and the check
+}
+
+// observe reports a completed check to the check hook, recovering and
+// returning a panic.
+func (m *Monitor[K, T]) observe(info CheckInfo) (recovered any) {
+	defer func() {
+		recovered = recover()
+	}()
+
+	m.checkHook(info)
+
+	return nil
+}
@@ -0,0 +1,379 @@
+package timestate
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Option configures optional Monitor settings.
+type Option[K comparable, T any] func(*Monitor[K, T])
+
+// WithCheckInterval sets how often expirations are checked.
+func WithCheckInterval[K comparable, T any](d time.Duration) Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.checkInterval = d
+	}
+}
+
+// WithDefaultTTL sets the state lifetime used by Watch.
+func WithDefaultTTL[K comparable, T any](ttl time.Duration) Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.defaultTTL = ttl
+	}
+}
+
+// WithExpiredChannel sets the channel for expiration notifications.
+func WithExpiredChannel[K comparable, T any](ch chan<- K) Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.expiredCh = ch
+	}
+}
+
+// WithMaxSize limits the number of tracked states.
+// When a new key would exceed the limit, the soonest-to-expire state
+// is evicted without expiration notification, unless another policy is
+// set by WithEvictionPolicy. Updates of existing keys never trigger
+// eviction. Zero or negative n means no limit.
+func WithMaxSize[K comparable, T any](n int) Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.maxSize = n
+	}
+}
+
+// WithEvictionPolicy sets how room is made for new states when the
+// WithMaxSize limit is reached. The default is EvictSoonest. Evicted keys
+// are reported to the WithEvictedChannel channel, separately from
+// expirations; rejected ones are counted in Stats.TotalRejected.
+func WithEvictionPolicy[K comparable, T any](policy EvictionPolicy) Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.eviction = policy
+	}
+}
+
+// WithEvictedChannel sets a channel for eviction notifications,
+// separate from expirations. Sends never block: if the channel is full,
+// the notification is dropped.
+func WithEvictedChannel[K comparable, T any](ch chan<- K) Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.evictedCh = ch
+	}
+}
+
+// WithErrorHandler sets a handler for panics recovered while delivering
+// expiration notifications, e.g. a send on a closed channel. The state
+// that caused the panic is dropped and the remaining expirations are
+// processed as usual. The handler is called from the background goroutine
+// outside the monitor lock.
+func WithErrorHandler[K comparable, T any](fn func(any)) Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.onError = fn
+	}
+}
+
+// WithJitter randomly perturbs each computed TTL by up to ±fraction of its
+// value (e.g., 0.1 for ±10%) to spread out expirations of states watched
+// at the same time. Deadlines set by WatchUntil are not affected.
+func WithJitter[K comparable, T any](fraction float64) Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.jitter = fraction
+	}
+}
+
+// WithRefreshOnGet makes Get count as activity: reading a state resets its
+// deadline using the TTL it was last watched with. States added with
+// WatchUntil keep their absolute deadline. By default Get is read-only.
+func WithRefreshOnGet[K comparable, T any]() Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.refreshOnGet = true
+	}
+}
+
+// WithStartDelay postpones the first expiration check until d after Start.
+// Watch and Get work immediately; states that expire during the delay are
+// delivered by the first check right after it.
+func WithStartDelay[K comparable, T any](d time.Duration) Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.startDelay = d
+	}
+}
+
+// WithCompactionThreshold sets the share of unused capacity of the
+// expiration heap (0..1) above which its backing array is reallocated
+// during the next check, releasing memory after many states are removed.
+// The default is 0.5. Zero or negative value disables compaction.
+func WithCompactionThreshold[K comparable, T any](f float64) Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.compactRatio = f
+	}
+}
+
+// WithBlockingDelivery makes the background checker wait until each
+// expiration notification is received instead of requeueing it while the
+// channel is full. Expired states are collected under the lock and sent
+// after it is released, so a slow consumer never blocks Get or Watch.
+// Notifications interrupted by Stop are kept and delivered by Shutdown.
+func WithBlockingDelivery[K comparable, T any]() Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.overflow = Block
+	}
+}
+
+// WithOverflowPolicy sets what happens to expiration notifications while
+// the channel is full. The default is Requeue. Block is the same as
+// WithBlockingDelivery. With the drop policies, states whose notification
+// was discarded are no longer tracked and are not passed to expiration
+// callbacks; they are counted in Stats.TotalDropped.
+func WithOverflowPolicy[K comparable, T any](policy OverflowPolicy) Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.overflow = policy
+	}
+}
+
+// WithBlockTimeout limits how long blocking delivery waits for a receiver.
+// A notification not received in time is discarded and counted in
+// Stats.TotalDropped; the state is still reported as expired to callbacks.
+// Has no effect unless blocking delivery is enabled.
+func WithBlockTimeout[K comparable, T any](d time.Duration) Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.blockTimeout = d
+	}
+}
+
+// WithTimingDiagnostics enables measuring how late expirations are
+// delivered, reported by Timing. Disabled by default to avoid overhead.
+func WithTimingDiagnostics[K comparable, T any]() Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.timing = new(timing)
+	}
+}
+
+// WithWarnThreshold enables warnings sent to ch once per state when the
+// given fraction of its lifetime has elapsed (e.g., 0.8 for 80%), before
+// the final expiration. Resetting the TTL re-arms the warning. Sends never
+// block: while the channel is full, the warning is retried on later checks.
+func WithWarnThreshold[K comparable, T any](fraction float64, ch chan<- K) Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.warnRatio = fraction
+		m.warnCh = ch
+	}
+}
+
+// WithWarnFunc is like WithWarnThreshold but calls fn with the key and its
+// current value instead of sending to a channel. Both may be used together.
+// The callback runs in the background goroutine outside the lock; its
+// panics are recovered and reported to the WithErrorHandler handler.
+func WithWarnFunc[K comparable, T any](fraction float64, fn func(key K, value T)) Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.warnRatio = fraction
+		m.warnFunc = fn
+	}
+}
+
+// WithDeadlineRounding rounds computed deadlines up to the next multiple
+// of d (e.g., 5*time.Second), coalescing nearby expirations into one check.
+// Combined with WithJitter, rounding is applied after jitter, so a quantum
+// larger than the jitter range cancels it out. Deadlines set by WatchUntil
+// are not rounded.
+func WithDeadlineRounding[K comparable, T any](d time.Duration) Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.rounding = d
+	}
+}
+
+// WithRefreshOnSameValue makes Watch with an unchanged value reset the TTL
+// while still returning false, which suits heartbeat-style liveness
+// tracking where reporting the same value means the state is still alive.
+// States added with WatchUntil keep their absolute deadline.
+// It is the same as WithPolicy(SlidingOnAnyWatch).
+func WithRefreshOnSameValue[K comparable, T any]() Option[K, T] {
+	return WithPolicy[K, T](SlidingOnAnyWatch)
+}
+
+// WithPolicy sets how watching existing states affects their deadlines.
+// The default is SlidingOnUpdate.
+func WithPolicy[K comparable, T any](policy ExpirationPolicy) Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.policy = policy
+	}
+}
+
+// WithExpireDebounce suppresses notifications for a key that expires again
+// within d of its previous notified expiration, e.g., after being
+// re-watched. Such states are still removed, only silently.
+func WithExpireDebounce[K comparable, T any](d time.Duration) Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.debounce = d
+		m.lastExpired = make(map[K]time.Time)
+	}
+}
+
+// WithExpiredEvents delivers expirations to ch as Expired events carrying
+// the last value, deadline and metadata instead of bare keys. When set,
+// the key channel passed to New is not used and may be nil; delivery
+// follows the same rules (requeue while full, or WithBlockingDelivery).
+func WithExpiredEvents[K comparable, T any](ch chan<- Expired[K, T]) Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.eventCh = ch
+	}
+}
+
+// WithExpiredBatches delivers all states expired by one check to ch as
+// batches of at most maxBatch events (zero or negative - one batch per
+// check), which is much cheaper than one send per key when many states
+// expire at once. When set, the key and event channels are not used.
+// Batches are sent after the lock is released, waiting for the receiver
+// like WithBlockingDelivery, limited by WithBlockTimeout.
+func WithExpiredBatches[K comparable, T any](ch chan<- []Expired[K, T], maxBatch int) Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.batchCh = ch
+		m.batchSize = maxBatch
+	}
+}
+
+// WithExpireFunc sets a callback invoked for every expired state with its
+// last value. Pass a nil channel to New to use the callback instead of
+// channel delivery, so expirations are never delayed by a full channel.
+// The callback runs in the background goroutine outside the lock; its
+// panics are recovered and reported to the WithErrorHandler handler.
+func WithExpireFunc[K comparable, T any](fn func(key K, value T)) Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.expireFunc = fn
+	}
+}
+
+// WithChangesChannel sets a channel receiving a Change for every added
+// state and every modified value. Sends never block: if the channel is
+// full, the notification is dropped.
+func WithChangesChannel[K comparable, T any](ch chan<- Change[K, T]) Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.changesCh = ch
+	}
+}
+
+// WithEvents sends an Event to ch whenever a value is gone: the state
+// expired, was removed or evicted, or its value was replaced. Unlike the
+// expiration channel, events are sent under the lock without blocking: if
+// the channel is full, the event is dropped.
+func WithEvents[K comparable, T any](ch chan<- Event[K, T]) Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.eventsCh = ch
+	}
+}
+
+// WithChangelog sends every change of tracked states to ch as numbered
+// records in the order they happen, so a follower monitor can mirror them
+// with Apply. Records are queued in memory and sent by a background
+// goroutine outside the lock, so a slow follower does not block the
+// monitor. At most maxPending records are queued (zero or negative - 1024);
+// further ones are dropped until the follower catches up, leaving a gap in
+// the sequence numbers, after which the follower should reload the states,
+// e.g. with Snapshot and Restore. Sending stops when the monitor stops.
+func WithChangelog[K comparable, T any](ch chan<- Record[K, T], maxPending int) Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.changelog = ch
+		m.logPending = maxPending
+		m.logStop = make(chan struct{})
+
+		if m.logPending <= 0 {
+			m.logPending = defaultLogPending
+		}
+	}
+}
+
+// WithCheckHook calls fn after every expiration check, outside the lock,
+// with its duration and the lateness of expired states. Use it to feed
+// metrics and trace spans, e.g. an OpenTelemetry histogram of lateness
+// and a span started at CheckInfo.Start. Panics in fn are recovered and
+// reported to the WithErrorHandler handler.
+func WithCheckHook[K comparable, T any](fn func(CheckInfo)) Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.checkHook = fn
+	}
+}
+
+// WithLogger logs otherwise silent conditions: notifications requeued or
+// dropped because the channel was full, timed out blocking deliveries and
+// checks taking longer than the check interval at Warn level, and every
+// check at Debug level.
+func WithLogger[K comparable, T any](logger *slog.Logger) Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.logger = logger
+	}
+}
+
+// WithHooks sets lifecycle callbacks. See Hooks for the locking rules.
+func WithHooks[K comparable, T any](hooks Hooks[K, T]) Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.hooks = hooks
+	}
+}
+
+// WithClock replaces the system clock used for deadlines and checks,
+// e.g., with FakeClock for deterministic tests.
+func WithClock[K comparable, T any](clock Clock) Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.clock = clock
+	}
+}
+
+// WithDeadlineTimer replaces the periodic check with a timer armed for the
+// earliest deadline, so states expire without waiting for the next tick
+// and an idle monitor does not wake up at all. The check interval is then
+// only used to retry notifications the channel could not accept and to
+// look for pending warnings.
+func WithDeadlineTimer[K comparable, T any]() Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.rearmCh = make(chan struct{}, 1)
+	}
+}
+
+// WithHash detects value changes by comparing hash(value) instead of the
+// values, which is cheaper for large states; colliding values are treated
+// as unchanged. With discard set, only the hash is kept to save memory:
+// Get, snapshots, events and expiration notifications then carry the zero
+// value of T, while Change notifications still carry the new value.
+func WithHash[K comparable, T any](hash func(T) uint64, discard bool) Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.hash = hash
+		m.discard = discard && hash != nil
+	}
+}
+
+// WithMaxExpirePerCheck limits how many states a single check expires
+// while holding the lock. The rest is carried over to the following
+// checks, which run right after the lock is released, so an expiration
+// storm does not block Watch and Get for its whole duration.
+func WithMaxExpirePerCheck[K comparable, T any](n int) Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.maxPerCheck = max(n, 0)
+	}
+}
+
+// WithAsyncDelivery sends expiration notifications from a dedicated
+// goroutine started by Start, so neither the lock nor the checker waits
+// for a slow consumer and no notification is requeued or dropped: they
+// are queued in memory until received instead. Without a running
+// dispatcher, e.g. with Flush before Start, notifications are sent like
+// WithBlockingDelivery. Notifications still queued when the dispatcher
+// stops, even during a check, are kept and delivered by Shutdown. Batches
+// set by WithExpiredBatches are still sent by the checker.
+func WithAsyncDelivery[K comparable, T any]() Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.async = true
+		m.outWake = make(chan struct{}, 1)
+	}
+}
+
+// WithGracePeriod gives states a second chance: on reaching the deadline
+// a state becomes stale, its key is sent to staleCh (if not nil) and it
+// stays tracked for d more; only then it expires as usual. This models
+// "degraded, then down" health checks with one monitor. A Watch that
+// extends the deadline during the grace period makes the state fresh
+// again. Stale notifications are not dropped: while staleCh is full they
+// are retried on the next check. PopExpired ignores the grace period.
+func WithGracePeriod[K comparable, T any](d time.Duration, staleCh chan<- K) Option[K, T] {
+	return func(m *Monitor[K, T]) {
+		m.grace = d
+		m.staleCh = staleCh
+	}
+}
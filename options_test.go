@@ -0,0 +1,967 @@
+package timestate_test
+
+import (
+	"bytes"
+	"context"
+	"hash/maphash"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mdigger/timestate"
+)
+
+func TestMaxSize(t *testing.T) {
+	evictedCh := make(chan string, 1)
+	monitor := timestate.New(time.Second, time.Minute, make(chan string, 1),
+		timestate.WithMaxSize[string, int](2),
+		timestate.WithEvictedChannel[string, int](evictedCh),
+	)
+
+	monitor.WatchWithTTL("short", 1, time.Second)
+	monitor.WatchWithTTL("long", 1, time.Hour)
+
+	// Updating an existing key must not evict
+	monitor.Watch("short", 2)
+
+	select {
+	case key := <-evictedCh:
+		t.Fatalf("Unexpected eviction: %s", key)
+	default:
+	}
+
+	monitor.Watch("new", 1)
+
+	select {
+	case key := <-evictedCh:
+		if key != "short" {
+			t.Errorf("Unexpected evicted key: %s", key)
+		}
+	default:
+		t.Fatal("Expected eviction notification")
+	}
+
+	if _, _, exists := monitor.Get("short"); exists {
+		t.Error("Evicted state should be removed")
+	}
+
+	if stats := monitor.Stats(); stats.Live != 2 || stats.TotalEvicted != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	checkInvariants(t, monitor)
+}
+
+func TestEvictionPolicy(t *testing.T) {
+	evictedCh := make(chan string, 1)
+	monitor := timestate.New(time.Second, time.Minute, make(chan string, 1),
+		timestate.WithMaxSize[string, int](2),
+		timestate.WithEvictionPolicy[string, int](timestate.EvictLRU),
+		timestate.WithEvictedChannel[string, int](evictedCh),
+	)
+
+	monitor.WatchWithTTL("old", 1, time.Hour)
+	monitor.WatchWithTTL("recent", 1, time.Second)
+	monitor.Watch("old", 1) // unchanged, but still a use
+	monitor.Watch("new", 1)
+
+	if key := <-evictedCh; key != "recent" {
+		t.Errorf("Unexpected evicted key: %s", key)
+	}
+
+	checkInvariants(t, monitor)
+
+	rejecting := timestate.New(time.Second, time.Minute, make(chan string, 1),
+		timestate.WithMaxSize[string, int](1),
+		timestate.WithEvictionPolicy[string, int](timestate.RejectNew),
+	)
+
+	rejecting.Watch("first", 1)
+
+	if rejecting.Watch("second", 1) || rejecting.WatchWithCallback("third", 1, nil) {
+		t.Error("New state should be rejected")
+	}
+
+	if stats := rejecting.Stats(); stats.Live != 1 || stats.TotalRejected != 2 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	errCh := make(chan any, 10)
+	expiredCh := make(chan string, 10)
+	monitor := timestate.New(50*time.Millisecond, time.Minute, expiredCh,
+		timestate.WithErrorHandler[string, int](func(r any) { errCh <- r }),
+	)
+
+	monitor.WatchWithTTL("first", 1, 10*time.Millisecond)
+	monitor.WatchWithTTL("second", 2, 20*time.Millisecond)
+	close(expiredCh) // every delivery panics now
+
+	ctx := t.Context()
+	monitor.Start(ctx)
+
+	for range 2 {
+		select {
+		case <-errCh:
+		case <-time.After(500 * time.Millisecond):
+			t.Fatal("Panic was not reported")
+		}
+	}
+
+	if _, _, exists := monitor.Get("second"); exists {
+		t.Error("State should be dropped after panic")
+	}
+
+	// the checker must keep running after panics
+	monitor.WatchWithTTL("third", 3, 10*time.Millisecond)
+
+	select {
+	case <-errCh:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Checker stopped after panic")
+	}
+}
+
+func TestJitter(t *testing.T) {
+	const ttl = time.Minute
+	monitor := timestate.New(time.Second, ttl, make(chan int, 1),
+		timestate.WithJitter[int, int](0.5),
+	)
+
+	start := time.Now()
+	var earliest, latest time.Duration
+
+	for i := range 100 {
+		monitor.Watch(i, i)
+		_, expires, _ := monitor.Get(i)
+
+		d := expires.Sub(start)
+		if d < ttl/2 || d > ttl*3/2+time.Second {
+			t.Fatalf("Deadline out of jitter range: %v", d)
+		}
+
+		if i == 0 || d < earliest {
+			earliest = d
+		}
+
+		if d > latest {
+			latest = d
+		}
+	}
+
+	if latest-earliest < ttl/4 {
+		t.Errorf("Deadlines are clustered: spread %v", latest-earliest)
+	}
+}
+
+func TestRefreshOnGet(t *testing.T) {
+	for _, refresh := range []bool{false, true} {
+		var opts []timestate.Option[string, int]
+		if refresh {
+			opts = append(opts, timestate.WithRefreshOnGet[string, int]())
+		}
+
+		monitor := timestate.New(time.Second, time.Minute, make(chan string, 1), opts...)
+		monitor.Watch("key", 1)
+		deadline := time.Now().Add(time.Hour)
+		monitor.WatchUntil("fixed", 1, deadline)
+
+		_, first, _ := monitor.Get("key")
+		time.Sleep(10 * time.Millisecond)
+		_, second, _ := monitor.Get("key")
+
+		if got := second.After(first); got != refresh {
+			t.Errorf("refresh=%v: deadline extended = %v", refresh, got)
+		}
+
+		if _, expires, _ := monitor.Get("fixed"); !expires.Equal(deadline) {
+			t.Errorf("refresh=%v: absolute deadline changed", refresh)
+		}
+	}
+}
+
+func TestStartDelay(t *testing.T) {
+	expiredCh := make(chan string, 1)
+	monitor := timestate.New(10*time.Millisecond, 10*time.Millisecond, expiredCh,
+		timestate.WithStartDelay[string, int](200*time.Millisecond),
+	)
+	ctx := t.Context()
+	monitor.Start(ctx)
+	monitor.Watch("key", 1)
+
+	select {
+	case <-expiredCh:
+		t.Fatal("Notification during start delay")
+	case <-time.After(150 * time.Millisecond):
+	}
+
+	select {
+	case <-expiredCh:
+	case <-time.After(500 * time.Millisecond):
+		t.Error("State did not expire after start delay")
+	}
+}
+
+func TestBlockingDelivery(t *testing.T) {
+	expiredCh := make(chan int) // unbuffered, nobody reads yet
+	monitor := timestate.New(10*time.Millisecond, 10*time.Millisecond, expiredCh,
+		timestate.WithBlockingDelivery[int, int](),
+	)
+	ctx := t.Context()
+	monitor.Start(ctx)
+
+	const count = 100
+	for i := range count {
+		monitor.Watch(i, i)
+	}
+
+	time.Sleep(50 * time.Millisecond) // let the sweep start sending
+
+	done := make(chan struct{})
+	go func() {
+		monitor.Get(0)
+		monitor.Watch(count, count)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("Monitor is locked during blocking delivery")
+	}
+
+	for range count {
+		select {
+		case <-expiredCh:
+		case <-time.After(500 * time.Millisecond):
+			t.Fatal("Expiration was not delivered")
+		}
+	}
+}
+
+func TestBlockingDeliveryShutdown(t *testing.T) {
+	expiredCh := make(chan string) // unbuffered, nobody reads yet
+	monitor := timestate.New(time.Millisecond, time.Millisecond, expiredCh,
+		timestate.WithBlockingDelivery[string, int](),
+	)
+	monitor.Start(t.Context())
+	monitor.Watch("a", 1)
+
+	// wait until the checker is blocked sending the notification
+	for deadline := time.Now().Add(time.Second); monitor.Stats().TotalExpired == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("State did not expire")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	monitor.Stop() // interrupts the send
+
+	received := make(chan string, 1)
+	go func() { received <- <-expiredCh }()
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+	defer cancel()
+
+	if err := monitor.Shutdown(ctx); err != nil {
+		t.Errorf("Shutdown failed: %v", err)
+	}
+
+	select {
+	case key := <-received:
+		if key != "a" {
+			t.Errorf("Expected a, got %s", key)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Notification interrupted by Stop was lost")
+	}
+
+	if stats := monitor.Stats(); stats.TotalDropped != 0 {
+		t.Errorf("Expected no drops, got %d", stats.TotalDropped)
+	}
+}
+
+func TestTimingDiagnostics(t *testing.T) {
+	expiredCh := make(chan string, 10)
+	monitor := timestate.New(50*time.Millisecond, 10*time.Millisecond, expiredCh,
+		timestate.WithTimingDiagnostics[string, int](),
+	)
+	ctx := t.Context()
+	monitor.Start(ctx)
+
+	monitor.Watch("a", 1)
+	monitor.Watch("b", 1)
+
+	for range 2 {
+		select {
+		case <-expiredCh:
+		case <-time.After(500 * time.Millisecond):
+			t.Fatal("State did not expire as expected")
+		}
+	}
+
+	timing := monitor.Timing()
+	if timing.Count != 2 || timing.Max <= 0 || timing.Average > timing.Max {
+		t.Errorf("Unexpected timing: %+v", timing)
+	}
+}
+
+func TestDeadlineRounding(t *testing.T) {
+	const quantum = 5 * time.Second
+	monitor := timestate.New(time.Second, 7*time.Second, make(chan int, 1),
+		timestate.WithDeadlineRounding[int, int](quantum),
+	)
+
+	start := time.Now()
+	monitor.Watch(1, 1)
+	_, expires, _ := monitor.Get(1)
+
+	if !expires.Truncate(quantum).Equal(expires) {
+		t.Errorf("Deadline is not on a boundary: %v", expires)
+	}
+
+	if d := expires.Sub(start); d < 7*time.Second || d >= 7*time.Second+quantum {
+		t.Errorf("Deadline out of range: %v", d)
+	}
+}
+
+func TestRefreshOnSameValue(t *testing.T) {
+	expiredCh := make(chan string, 1)
+	monitor := timestate.New(10*time.Millisecond, 50*time.Millisecond, expiredCh,
+		timestate.WithRefreshOnSameValue[string, int](),
+	)
+	ctx := t.Context()
+	monitor.Start(ctx)
+
+	monitor.Watch("heartbeat", 1)
+
+	for range 10 {
+		time.Sleep(20 * time.Millisecond)
+
+		if monitor.Watch("heartbeat", 1) {
+			t.Fatal("Expected false for unchanged state")
+		}
+	}
+
+	select {
+	case <-expiredCh:
+		t.Fatal("Constant heartbeat expired")
+	default:
+	}
+
+	select {
+	case <-expiredCh:
+	case <-time.After(500 * time.Millisecond):
+		t.Error("State did not expire after heartbeats stopped")
+	}
+}
+
+func TestEvictionPriority(t *testing.T) {
+	evictedCh := make(chan string, 10)
+	monitor := timestate.New(time.Second, time.Minute, make(chan string, 1),
+		timestate.WithMaxSize[string, int](3),
+		timestate.WithEvictedChannel[string, int](evictedCh),
+	)
+
+	monitor.WatchWithPriority("important", 1, 10)
+	monitor.WatchWithPriority("low-long", 1, 1)
+	monitor.WatchWithPriority("low-short", 1, 1)
+	monitor.SetDefaultTTL(time.Second)
+	monitor.WatchWithPriority("low-short", 2, 1) // sooner deadline
+	monitor.SetDefaultTTL(time.Hour)
+
+	monitor.WatchWithPriority("new1", 1, 5)
+	monitor.WatchWithPriority("new2", 1, 5)
+
+	for _, want := range []string{"low-short", "low-long"} {
+		select {
+		case key := <-evictedCh:
+			if key != want {
+				t.Errorf("Unexpected evicted key: got %s, want %s", key, want)
+			}
+		default:
+			t.Fatal("Expected eviction notification")
+		}
+	}
+
+	if !monitor.Has("important") {
+		t.Error("High-priority state should stay despite the earliest deadline")
+	}
+
+	checkInvariants(t, monitor)
+}
+
+func TestExpireDebounce(t *testing.T) {
+	expiredCh := make(chan string, 10)
+	monitor := timestate.New(10*time.Millisecond, 10*time.Millisecond, expiredCh,
+		timestate.WithExpireDebounce[string, int](300*time.Millisecond),
+	)
+	ctx := t.Context()
+	monitor.Start(ctx)
+
+	expect := func(notified bool) {
+		t.Helper()
+
+		select {
+		case <-expiredCh:
+			if !notified {
+				t.Fatal("Duplicate expiration was not suppressed")
+			}
+		case <-time.After(100 * time.Millisecond):
+			if notified {
+				t.Fatal("Expiration was not delivered")
+			}
+		}
+	}
+
+	monitor.Watch("key", 1)
+	expect(true)
+
+	monitor.Watch("key", 1) // re-added within the window
+	expect(false)
+
+	if monitor.Has("key") {
+		t.Error("Suppressed state should be removed")
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	monitor.Watch("key", 1) // after the window
+	expect(true)
+}
+
+func TestExpiredEvents(t *testing.T) {
+	eventCh := make(chan timestate.Expired[string, int], 1)
+	monitor := timestate.New(10*time.Millisecond, time.Minute, nil,
+		timestate.WithExpiredEvents(eventCh),
+	)
+	ctx := t.Context()
+	monitor.Start(ctx)
+
+	deadline := time.Now().Add(20 * time.Millisecond)
+	monitor.WatchWithMeta("key", 1, "origin")
+	monitor.WatchUntil("key", 2, deadline)
+
+	select {
+	case event := <-eventCh:
+		if event.Key != "key" || event.Value != 2 ||
+			!event.Expires.Equal(deadline) || event.Meta != "origin" {
+			t.Errorf("Unexpected event: %+v", event)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expiration event was not delivered")
+	}
+}
+
+func TestExpireFunc(t *testing.T) {
+	type expired struct {
+		key   string
+		value int
+	}
+
+	fired := make(chan expired, 10)
+	monitor := timestate.New(10*time.Millisecond, 10*time.Millisecond, nil,
+		timestate.WithExpireFunc(func(key string, value int) {
+			fired <- expired{key, value}
+		}),
+	)
+	ctx := t.Context()
+	monitor.Start(ctx)
+
+	monitor.Watch("key", 42)
+
+	select {
+	case e := <-fired:
+		if e.key != "key" || e.value != 42 {
+			t.Errorf("Unexpected callback: %+v", e)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expire callback was not called")
+	}
+}
+
+func TestNewWithOptions(t *testing.T) {
+	expiredCh := make(chan string, 1)
+	monitor := timestate.NewWithOptions(
+		timestate.WithCheckInterval[string, int](10*time.Millisecond),
+		timestate.WithDefaultTTL[string, int](10*time.Millisecond),
+		timestate.WithExpiredChannel[string, int](expiredCh),
+	)
+	ctx := t.Context()
+	monitor.Start(ctx)
+
+	if ttl := monitor.DefaultTTL(); ttl != 10*time.Millisecond {
+		t.Errorf("Unexpected default TTL: %v", ttl)
+	}
+
+	monitor.Watch("key", 1)
+
+	select {
+	case <-expiredCh:
+	case <-time.After(500 * time.Millisecond):
+		t.Error("State did not expire as expected")
+	}
+}
+
+func TestChangesChannel(t *testing.T) {
+	changesCh := make(chan timestate.Change[string, int], 10)
+	monitor := timestate.New(time.Second, time.Minute, make(chan string, 1),
+		timestate.WithChangesChannel(changesCh),
+	)
+
+	monitor.Watch("key", 1)
+	monitor.Watch("key", 1) // unchanged
+	monitor.Watch("key", 2)
+
+	want := []timestate.Change[string, int]{
+		{Key: "key", New: 1},
+		{Key: "key", Old: 1, New: 2, Existed: true},
+	}
+
+	if len(changesCh) != len(want) {
+		t.Fatalf("Unexpected number of changes: %d", len(changesCh))
+	}
+
+	for _, w := range want {
+		if got := <-changesCh; got != w {
+			t.Errorf("Unexpected change: got %+v, want %+v", got, w)
+		}
+	}
+}
+
+func TestOverflowPolicy(t *testing.T) {
+	tests := []struct {
+		policy timestate.OverflowPolicy
+		want   []int
+	}{
+		{timestate.DropOldest, []int{3, 4}},
+		{timestate.DropAndCount, []int{0, 1}},
+	}
+
+	for _, tt := range tests {
+		clock := timestate.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+		expiredCh := make(chan int, 2)
+		monitor := timestate.New(time.Second, time.Minute, expiredCh,
+			timestate.WithClock[int, int](clock),
+			timestate.WithOverflowPolicy[int, int](tt.policy),
+		)
+
+		for i := range 5 {
+			monitor.WatchWithTTL(i, i, time.Duration(i+1)*time.Second)
+		}
+
+		clock.Advance(time.Minute)
+		monitor.Flush()
+
+		for _, want := range tt.want {
+			if got := <-expiredCh; got != want {
+				t.Errorf("Policy %d: expected %d, got %d", tt.policy, want, got)
+			}
+		}
+
+		if stats := monitor.Stats(); stats.TotalDropped != 3 || stats.Live != 0 {
+			t.Errorf("Policy %d: unexpected stats %+v", tt.policy, stats)
+		}
+	}
+}
+
+func TestBlockTimeout(t *testing.T) {
+	monitor := timestate.New(time.Hour, time.Nanosecond, make(chan int),
+		timestate.WithBlockingDelivery[int, int](),
+		timestate.WithBlockTimeout[int, int](10*time.Millisecond),
+	)
+
+	monitor.Watch(1, 1)
+	time.Sleep(time.Millisecond)
+
+	if n := monitor.Flush(); n != 1 {
+		t.Fatalf("Unexpected expired count: %d", n)
+	}
+
+	if stats := monitor.Stats(); stats.TotalDropped != 1 {
+		t.Errorf("Timed out notification not counted: %+v", stats)
+	}
+}
+
+func TestExpiredBatches(t *testing.T) {
+	batchCh := make(chan []timestate.Expired[int, int], 10)
+	monitor := timestate.NewWithOptions(
+		timestate.WithDefaultTTL[int, int](time.Nanosecond),
+		timestate.WithExpiredBatches(batchCh, 4),
+	)
+
+	for i := range 10 {
+		monitor.Watch(i, i)
+	}
+
+	time.Sleep(time.Millisecond)
+
+	if n := monitor.Flush(); n != 10 {
+		t.Fatalf("Unexpected expired count: %d", n)
+	}
+
+	var sizes []int
+
+	for len(batchCh) > 0 {
+		batch := <-batchCh
+		sizes = append(sizes, len(batch))
+	}
+
+	if !slices.Equal(sizes, []int{4, 4, 2}) {
+		t.Errorf("Unexpected batch sizes: %v", sizes)
+	}
+}
+
+func TestCheckHook(t *testing.T) {
+	clock := timestate.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	infos := make(chan timestate.CheckInfo, 1)
+	monitor := timestate.New(time.Second, time.Minute, make(chan string, 1),
+		timestate.WithClock[string, int](clock),
+		timestate.WithCheckHook[string, int](func(info timestate.CheckInfo) { infos <- info }),
+	)
+
+	monitor.Watch("key", 1)
+	clock.Advance(time.Minute + time.Second)
+	monitor.Flush()
+
+	info := <-infos
+	if info.Expired != 1 || !slices.Equal(info.Lateness, []time.Duration{time.Second}) {
+		t.Errorf("Unexpected check info: %+v", info)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	monitor := timestate.New(time.Second, time.Nanosecond, make(chan string, 1),
+		timestate.WithLogger[string, int](logger),
+	)
+
+	monitor.Watch("a", 1)
+	monitor.Watch("b", 1)
+	monitor.Watch("c", 1)
+	time.Sleep(time.Millisecond)
+	monitor.Flush()
+
+	out := buf.String()
+	if !strings.Contains(out, "expiration channel full") || !strings.Contains(out, "policy=requeue") ||
+		!strings.Contains(out, "notifications=2") {
+		t.Errorf("Full channel not logged:\n%s", out)
+	}
+
+	if stats := monitor.Stats(); stats.TotalDropped != 2 {
+		t.Errorf("Expected 2 requeued notifications, got %d", stats.TotalDropped)
+	}
+
+	if !strings.Contains(out, "level=DEBUG") {
+		t.Errorf("Check summary not logged:\n%s", out)
+	}
+}
+
+func TestHash(t *testing.T) {
+	seed := maphash.MakeSeed()
+	hash := func(s string) uint64 { return maphash.String(seed, s) }
+	monitor := timestate.New(time.Second, time.Minute, make(chan string, 1),
+		timestate.WithHash[string, string](hash, true),
+	)
+
+	if !monitor.Watch("blob", "large value") {
+		t.Error("New state should be reported as updated")
+	}
+
+	if monitor.Watch("blob", "large value") {
+		t.Error("Same value should not be reported as updated")
+	}
+
+	if !monitor.Watch("blob", "other value") {
+		t.Error("Changed value should be reported as updated")
+	}
+
+	if value, _, ok := monitor.Get("blob"); !ok || value != "" {
+		t.Errorf("Value should be discarded, got %q", value)
+	}
+
+	if monitor.CompareAndSwap("blob", "large value", "x") {
+		t.Error("CompareAndSwap should compare hashes")
+	}
+
+	if !monitor.CompareAndSwap("blob", "other value", "x") {
+		t.Error("CompareAndSwap should match by hash")
+	}
+}
+
+func TestMaxExpirePerCheck(t *testing.T) {
+	clock := timestate.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	var checks []int
+
+	expiredCh := make(chan int, 10)
+	monitor := timestate.New(time.Second, time.Minute, expiredCh,
+		timestate.WithClock[int, int](clock),
+		timestate.WithMaxExpirePerCheck[int, int](2),
+		timestate.WithCheckHook[int, int](func(info timestate.CheckInfo) {
+			checks = append(checks, info.Expired)
+		}),
+	)
+
+	for i := range 5 {
+		monitor.WatchWithTTL(i, i, time.Duration(i+1)*time.Second)
+	}
+
+	clock.Advance(time.Minute)
+
+	if n := monitor.Flush(); n != 5 {
+		t.Errorf("Expected 5 expired states, got %d", n)
+	}
+
+	if !slices.Equal(checks, []int{2, 2, 1}) {
+		t.Errorf("Unexpected expirations per check: %v", checks)
+	}
+
+	for i := range 5 {
+		if key := <-expiredCh; key != i {
+			t.Errorf("Expected %d, got %d", i, key)
+		}
+	}
+}
+
+func TestMaxExpirePerCheckFullChannel(t *testing.T) {
+	clock := timestate.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	expiredCh := make(chan int, 1)
+	monitor := timestate.New(time.Second, time.Minute, expiredCh,
+		timestate.WithClock[int, int](clock),
+		timestate.WithMaxExpirePerCheck[int, int](2),
+	)
+
+	for i := range 5 {
+		monitor.Watch(i, i)
+	}
+
+	clock.Advance(time.Minute)
+
+	if n := monitor.Flush(); n != 1 {
+		t.Errorf("Expected 1 delivered state, got %d", n)
+	}
+
+	if monitor.Len() != 4 {
+		t.Errorf("Undelivered states should be requeued, got %d", monitor.Len())
+	}
+}
+
+func TestAsyncDelivery(t *testing.T) {
+	expiredCh := make(chan string) // unbuffered: nobody receives yet
+	monitor := timestate.New(5*time.Millisecond, time.Hour, expiredCh,
+		timestate.WithAsyncDelivery[string, int](),
+	)
+	monitor.Start(t.Context())
+
+	monitor.WatchWithTTL("a", 1, 10*time.Millisecond)
+	monitor.WatchWithTTL("b", 2, 20*time.Millisecond)
+
+	deadline := time.Now().Add(time.Second)
+	for monitor.Len() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("States should expire while the consumer is blocked")
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if !monitor.Watch("c", 3) {
+		t.Error("Watch should not be blocked by pending notifications")
+	}
+
+	for _, want := range []string{"a", "b"} {
+		select {
+		case key := <-expiredCh:
+			if key != want {
+				t.Errorf("Expected %s, got %s", want, key)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Notification for %s was not delivered", want)
+		}
+	}
+
+	if stats := monitor.Stats(); stats.TotalDropped != 0 || stats.TotalExpired != 2 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestAsyncDeliveryBatches(t *testing.T) {
+	expiredCh := make(chan string, 1)
+	batchCh := make(chan []timestate.Expired[string, int], 1)
+	monitor := timestate.New(5*time.Millisecond, time.Millisecond, expiredCh,
+		timestate.WithAsyncDelivery[string, int](),
+		timestate.WithExpiredBatches(batchCh, 0),
+	)
+	monitor.Start(t.Context())
+	monitor.Watch("a", 1)
+
+	select {
+	case batch := <-batchCh:
+		if len(batch) != 1 || batch[0].Key != "a" {
+			t.Errorf("Unexpected batch: %v", batch)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Batch was not delivered")
+	}
+
+	monitor.Stop()
+
+	select {
+	case key := <-expiredCh:
+		t.Errorf("Key channel should not be used with batches, got %s", key)
+	default:
+	}
+}
+
+func TestAsyncDeliveryShutdown(t *testing.T) {
+	expiredCh := make(chan string)
+	monitor := timestate.New(5*time.Millisecond, time.Hour, expiredCh,
+		timestate.WithAsyncDelivery[string, int](),
+	)
+	monitor.Start(t.Context())
+	monitor.WatchWithTTL("a", 1, time.Millisecond)
+
+	deadline := time.Now().Add(time.Second)
+	for monitor.Len() > 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	monitor.Stop()
+
+	go func() {
+		if key := <-expiredCh; key != "a" {
+			t.Errorf("Expected a, got %s", key)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+	defer cancel()
+
+	if err := monitor.Shutdown(ctx); err != nil {
+		t.Errorf("Shutdown should deliver queued notifications: %v", err)
+	}
+}
+
+func TestGracePeriod(t *testing.T) {
+	clock := timestate.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	expiredCh := make(chan string, 1)
+	staleCh := make(chan string, 1)
+	monitor := timestate.New(time.Second, time.Minute, expiredCh,
+		timestate.WithClock[string, int](clock),
+		timestate.WithGracePeriod[string, int](30*time.Second, staleCh),
+	)
+
+	monitor.Watch("db", 1)
+	clock.Advance(time.Minute)
+
+	if n := monitor.Flush(); n != 0 {
+		t.Errorf("State should not expire at the first deadline, got %d", n)
+	}
+
+	if key := <-staleCh; key != "db" || !monitor.Stale("db") {
+		t.Errorf("State should become stale, got %s", key)
+	}
+
+	monitor.Touch("db")
+
+	if monitor.Stale("db") {
+		t.Error("Touch should make the state fresh again")
+	}
+
+	clock.Advance(time.Minute)
+	monitor.Flush()
+	<-staleCh
+
+	clock.Advance(30 * time.Second)
+
+	if n := monitor.Flush(); n != 1 || monitor.Has("db") {
+		t.Errorf("State should expire after the grace period, got %d", n)
+	}
+
+	if key := <-expiredCh; key != "db" {
+		t.Errorf("Expected db, got %s", key)
+	}
+}
+
+func TestGracePeriodFullChannel(t *testing.T) {
+	clock := timestate.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	staleCh := make(chan string) // never ready
+	monitor := timestate.New(time.Second, time.Minute, make(chan string, 1),
+		timestate.WithClock[string, int](clock),
+		timestate.WithGracePeriod[string, int](30*time.Second, staleCh),
+		timestate.WithMaxExpirePerCheck[string, int](1),
+	)
+
+	monitor.Watch("a", 1)
+	monitor.Watch("b", 2)
+	clock.Advance(time.Minute)
+
+	done := make(chan int)
+	go func() { done <- monitor.Flush() }()
+
+	select {
+	case n := <-done:
+		if n != 0 || monitor.Len() != 2 || monitor.Stale("a") {
+			t.Errorf("States should wait for the stale channel, expired %d", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Flush should not spin while the stale channel is full")
+	}
+}
+
+func TestAsyncDeliveryStopDuringCheck(t *testing.T) {
+	clock := timestate.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	entered, release := make(chan struct{}), make(chan struct{})
+	expiredCh := make(chan string)
+	monitor := timestate.New(time.Minute, time.Minute, expiredCh,
+		timestate.WithClock[string, int](clock),
+		timestate.WithAsyncDelivery[string, int](),
+		// the warning callback holds the check after states were expired
+		timestate.WithWarnFunc[string, int](0.5, func(string, int) {
+			close(entered)
+			<-release
+		}),
+	)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	monitor.Start(ctx)
+	monitor.WatchWithTTL("a", 1, time.Minute)
+	monitor.WatchWithTTL("w", 1, 2*time.Minute)
+	clock.Advance(time.Minute)
+
+	select {
+	case <-entered:
+	case <-time.After(time.Second):
+		t.Fatal("Check did not start")
+	}
+
+	cancel()
+	time.Sleep(50 * time.Millisecond) // let the dispatcher exit
+	close(release)
+	monitor.Stop()
+
+	received := make(chan string, 1)
+	go func() { received <- <-expiredCh }()
+
+	shutdownCtx, stop := context.WithTimeout(t.Context(), time.Second)
+	defer stop()
+
+	if err := monitor.Shutdown(shutdownCtx); err != nil {
+		t.Errorf("Shutdown failed: %v", err)
+	}
+
+	select {
+	case key := <-received:
+		if key != "a" {
+			t.Errorf("Expected a, got %s", key)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Notification expired during Stop was lost")
+	}
+}
@@ -0,0 +1,104 @@
+// Package otel records OpenTelemetry metrics and trace spans for the
+// expiration checks of a timestate.Monitor. It is a separate module, so
+// the core package stays free of dependencies.
+package otel
+
+import (
+	"context"
+
+	"github.com/mdigger/timestate"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// scope is the instrumentation scope name.
+const scope = "github.com/mdigger/timestate/otel"
+
+// Instrument returns an option recording every expiration check:
+//
+//   - timestate.check.duration: histogram of check durations in seconds;
+//   - timestate.expiration.lateness: histogram of delays between deadlines
+//     and notifications in seconds;
+//   - timestate.checks and timestate.expired: counters of checks and
+//     expired states;
+//   - a "timestate.check" span covering each check.
+//
+// The option is built on timestate.WithCheckHook and replaces a hook set
+// by it. Either provider may be nil to skip metrics or spans.
+func Instrument[K comparable, T any](mp metric.MeterProvider, tp trace.TracerProvider) (timestate.Option[K, T], error) {
+	r := new(recorder)
+
+	if mp != nil {
+		if err := r.init(mp.Meter(scope)); err != nil {
+			return nil, err
+		}
+	}
+
+	if tp != nil {
+		r.tracer = tp.Tracer(scope)
+	}
+
+	return timestate.WithCheckHook[K, T](r.record), nil
+}
+
+// recorder holds the instruments.
+type recorder struct {
+	duration metric.Float64Histogram
+	lateness metric.Float64Histogram
+	checks   metric.Int64Counter
+	expired  metric.Int64Counter
+	tracer   trace.Tracer
+}
+
+// init creates the metric instruments.
+func (r *recorder) init(meter metric.Meter) (err error) {
+	r.duration, err = meter.Float64Histogram("timestate.check.duration",
+		metric.WithDescription("Duration of expiration checks, including delivery"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return err
+	}
+
+	r.lateness, err = meter.Float64Histogram("timestate.expiration.lateness",
+		metric.WithDescription("Delay between the deadline and the notification"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return err
+	}
+
+	r.checks, err = meter.Int64Counter("timestate.checks",
+		metric.WithDescription("Completed expiration checks"))
+	if err != nil {
+		return err
+	}
+
+	r.expired, err = meter.Int64Counter("timestate.expired",
+		metric.WithDescription("States delivered as expired"))
+
+	return err
+}
+
+// record reports a completed check.
+func (r *recorder) record(info timestate.CheckInfo) {
+	ctx := context.Background()
+
+	if r.tracer != nil {
+		_, span := r.tracer.Start(ctx, "timestate.check",
+			trace.WithTimestamp(info.Start),
+			trace.WithAttributes(attribute.Int("timestate.expired", info.Expired)))
+		span.End(trace.WithTimestamp(info.Start.Add(info.Duration)))
+	}
+
+	if r.checks == nil {
+		return
+	}
+
+	r.checks.Add(ctx, 1)
+	r.expired.Add(ctx, int64(info.Expired))
+	r.duration.Record(ctx, info.Duration.Seconds())
+
+	for _, late := range info.Lateness {
+		r.lateness.Record(ctx, late.Seconds())
+	}
+}
@@ -0,0 +1,60 @@
+package otel_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mdigger/timestate"
+	"github.com/mdigger/timestate/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestInstrument(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	spans := tracetest.NewSpanRecorder()
+
+	option, err := otel.Instrument[string, int](
+		sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+		sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock := timestate.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	monitor := timestate.New(time.Second, time.Minute, make(chan string, 2),
+		timestate.WithClock[string, int](clock), option)
+
+	monitor.Watch("a", 1)
+	monitor.Watch("b", 2)
+	clock.Advance(time.Minute + time.Second)
+	monitor.Flush()
+
+	if ended := spans.Ended(); len(ended) != 1 || ended[0].Name() != "timestate.check" {
+		t.Errorf("Expected one check span, got %d", len(ended))
+	}
+
+	var data metricdata.ResourceMetrics
+	if err := reader.Collect(t.Context(), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]metricdata.Aggregation)
+	for _, sm := range data.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			got[m.Name] = m.Data
+		}
+	}
+
+	if sum, ok := got["timestate.expired"].(metricdata.Sum[int64]); !ok || sum.DataPoints[0].Value != 2 {
+		t.Errorf("Unexpected expired counter: %+v", got["timestate.expired"])
+	}
+
+	lateness, ok := got["timestate.expiration.lateness"].(metricdata.Histogram[float64])
+	if !ok || lateness.DataPoints[0].Count != 2 || lateness.DataPoints[0].Sum != 2 {
+		t.Errorf("Unexpected lateness histogram: %+v", got["timestate.expiration.lateness"])
+	}
+}
@@ -0,0 +1,55 @@
+package timestate
+
+import "math"
+
+// OverflowPolicy defines what happens to an expiration notification when
+// the expiration channel is full.
+type OverflowPolicy int
+
+const (
+	// Requeue keeps the expired state and retries its notification on the
+	// next check. Notifications are never lost but may be delayed while
+	// the consumer is slow.
+	Requeue OverflowPolicy = iota
+	// Block waits until the notification is received, outside the lock,
+	// or until the timeout set by WithBlockTimeout elapses.
+	Block
+	// DropOldest discards the notifications with the earliest deadlines
+	// that do not fit into the free channel capacity, so the consumer
+	// receives the most recent expirations.
+	DropOldest
+	// DropAndCount discards notifications that do not fit into the
+	// channel, delivering the earliest ones.
+	DropAndCount
+)
+
+// String returns the policy name.
+func (p OverflowPolicy) String() string {
+	switch p {
+	case Requeue:
+		return "requeue"
+	case Block:
+		return "block"
+	case DropOldest:
+		return "drop-oldest"
+	case DropAndCount:
+		return "drop-and-count"
+	default:
+		return "unknown"
+	}
+}
+
+// room returns the free capacity of the notification channel.
+// Must be called with the lock held.
+func (m *Monitor[K, T]) room() int {
+	switch {
+	case m.batchCh != nil:
+		return math.MaxInt // batches are sent after the check
+	case m.eventCh != nil:
+		return cap(m.eventCh) - len(m.eventCh)
+	case m.expiredCh != nil:
+		return cap(m.expiredCh) - len(m.expiredCh)
+	default:
+		return math.MaxInt // notified by other means only
+	}
+}
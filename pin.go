@@ -0,0 +1,87 @@
+package timestate
+
+import (
+	"container/heap"
+	"time"
+)
+
+// Pin excludes a tracked state from expiration until Unpin. Its value is
+// still updated and change-detected by Watch, but deadline changes are
+// ignored while it is pinned: Get reports a zero deadline and TTL a zero
+// remaining lifetime. Returns false if the state is not tracked.
+func (m *Monitor[K, T]) Pin(key K) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	it, exists := m.items[key]
+	if !exists {
+		return false
+	}
+
+	if !it.pinned {
+		m.pin(it)
+	}
+
+	return true
+}
+
+// Unpin makes a pinned state expire again, after the TTL it was last
+// watched with (or the default TTL for states added with a deadline).
+// Returns false if the state is not tracked or not pinned.
+func (m *Monitor[K, T]) Unpin(key K) bool {
+	now := m.clock.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	it, exists := m.items[key]
+	if !exists || !it.pinned {
+		return false
+	}
+
+	ttl := it.ttl
+	if ttl <= 0 {
+		ttl = m.defaultTTL
+	}
+
+	m.unpin(it, m.expiresAt(now, ttl))
+
+	return true
+}
+
+// unschedule removes the item from the expiration heap unless it is pinned.
+// Must be called with the lock held.
+func (m *Monitor[K, T]) unschedule(it *item[K, T]) {
+	if !it.pinned {
+		heap.Remove(&m.heap, it.index)
+	}
+}
+
+// pin takes the item out of the expiration heap.
+// Must be called with the lock held.
+func (m *Monitor[K, T]) pin(it *item[K, T]) {
+	heap.Remove(&m.heap, it.index)
+	it.pinned = true
+	it.Expires = time.Time{}
+	m.disarm(it)
+	m.persist(it)
+
+	if m.maxSize > 0 {
+		heap.Fix(&m.byPriority, it.pindex)
+	}
+}
+
+// unpin schedules the pinned item to expire at expires.
+// Must be called with the lock held.
+func (m *Monitor[K, T]) unpin(it *item[K, T], expires time.Time) {
+	it.pinned = false
+	it.Expires = expires
+	m.armWarning(it)
+	heap.Push(&m.heap, it)
+	m.rearm(it)
+	m.persist(it)
+
+	if m.maxSize > 0 {
+		heap.Fix(&m.byPriority, it.pindex)
+	}
+}
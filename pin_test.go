@@ -0,0 +1,80 @@
+package timestate_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mdigger/timestate"
+)
+
+func TestPin(t *testing.T) {
+	clock := timestate.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	expiredCh := make(chan string, 2)
+	monitor := timestate.New(time.Second, time.Minute, expiredCh,
+		timestate.WithClock[string, int](clock),
+	)
+
+	monitor.Watch("core", 1)
+	monitor.Watch("edge", 1)
+
+	if !monitor.Pin("core") || monitor.Pin("missing") {
+		t.Fatal("Unexpected Pin result")
+	}
+
+	clock.Advance(time.Hour)
+
+	if n := monitor.Flush(); n != 1 || <-expiredCh != "edge" {
+		t.Fatalf("Only the unpinned state should expire: %d", n)
+	}
+
+	if !monitor.Watch("core", 2) {
+		t.Error("Pinned state change not detected")
+	}
+
+	restored := timestate.Restore(time.Second, time.Minute, expiredCh, monitor.Snapshot(),
+		timestate.WithClock[string, int](clock),
+	)
+	if restored.Flush() != 0 || !restored.Has("core") {
+		t.Error("Pinned state not restored")
+	}
+
+	checkInvariants(t, monitor)
+
+	if !monitor.Unpin("core") || monitor.Unpin("core") {
+		t.Fatal("Unexpected Unpin result")
+	}
+
+	clock.Advance(time.Minute)
+
+	if n := monitor.Flush(); n != 1 || <-expiredCh != "core" {
+		t.Errorf("Unpinned state did not expire: %d", n)
+	}
+}
+
+func TestPinnedDeadlineChanges(t *testing.T) {
+	monitor := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+	monitor.WatchInGroup("g", "a", 1)
+	monitor.Watch("b", 1)
+
+	before := monitor.ApproxMemory()
+
+	monitor.Pin("a")
+	monitor.Pin("b")
+
+	if after := monitor.ApproxMemory(); after < before*3/4 {
+		t.Errorf("Pinned states should stay in the estimate: %d, was %d", after, before)
+	}
+
+	if monitor.Touch("a") || monitor.TouchTTL("a", time.Hour) || monitor.SetTTL("a", time.Hour) ||
+		monitor.Extend("a", time.Hour) {
+		t.Error("Deadline changes of pinned states should report false")
+	}
+
+	if n := monitor.TouchGroup("g"); n != 0 {
+		t.Errorf("TouchGroup should skip pinned states, got %d", n)
+	}
+
+	if n := monitor.ExpireWhere(func(string, int) bool { return true }); n != 0 {
+		t.Errorf("ExpireWhere should skip pinned states, got %d", n)
+	}
+}
@@ -0,0 +1,16 @@
+package timestate
+
+// ExpirationPolicy defines how watching an existing state affects its
+// deadline.
+type ExpirationPolicy int
+
+const (
+	// SlidingOnUpdate resets the TTL only when the value changes.
+	SlidingOnUpdate ExpirationPolicy = iota
+	// SlidingOnAnyWatch resets the TTL on every Watch, even with an
+	// unchanged value. Useful for heartbeat liveness tracking.
+	SlidingOnAnyWatch
+	// Absolute expires states a TTL after they were first watched,
+	// regardless of later updates, as if added with WatchUntil.
+	Absolute
+)
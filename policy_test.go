@@ -0,0 +1,45 @@
+package timestate_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mdigger/timestate"
+)
+
+func TestExpirationPolicy(t *testing.T) {
+	tests := []struct {
+		policy    timestate.ExpirationPolicy
+		same      bool // unchanged Watch extends the deadline
+		different bool // changed Watch extends the deadline
+	}{
+		{policy: timestate.SlidingOnUpdate, same: false, different: true},
+		{policy: timestate.SlidingOnAnyWatch, same: true, different: true},
+		{policy: timestate.Absolute, same: false, different: false},
+	}
+
+	for _, tt := range tests {
+		monitor := timestate.New(time.Second, time.Minute, make(chan string, 1),
+			timestate.WithPolicy[string, int](tt.policy),
+		)
+
+		monitor.Watch("key", 1)
+		_, first, _ := monitor.Get("key")
+
+		time.Sleep(5 * time.Millisecond)
+		monitor.Watch("key", 1)
+		_, second, _ := monitor.Get("key")
+
+		time.Sleep(5 * time.Millisecond)
+		monitor.Watch("key", 2)
+		_, third, _ := monitor.Get("key")
+
+		if got := second.After(first); got != tt.same {
+			t.Errorf("policy %d: unchanged Watch extended = %v", tt.policy, got)
+		}
+
+		if got := third.After(second); got != tt.different {
+			t.Errorf("policy %d: changed Watch extended = %v", tt.policy, got)
+		}
+	}
+}
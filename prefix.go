@@ -0,0 +1,62 @@
+package timestate
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// KeysWithPrefix returns the keys starting with prefix in ascending order,
+// e.g. all "region/host/" services of hierarchical keys. It scans all
+// tracked states.
+func KeysWithPrefix[K ~string, T any](m *Monitor[K, T], prefix string) []K {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var keys []K
+
+	for key := range m.items {
+		if strings.HasPrefix(string(key), prefix) {
+			keys = append(keys, key)
+		}
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
+
+// RemovePrefix removes the states whose keys start with prefix without
+// expiration notifications and returns their number.
+func RemovePrefix[K ~string, T any](m *Monitor[K, T], prefix string) int {
+	return m.RemoveWhere(func(key K, _ T) bool {
+		return strings.HasPrefix(string(key), prefix)
+	})
+}
+
+// RangeByKey calls fn for each live state with a key in [from, to) in
+// ascending key order, until fn returns false. Like Monitor.Range, it
+// holds the read lock while fn runs, so fn must not call Monitor methods.
+func RangeByKey[K cmp.Ordered, T any](m *Monitor[K, T], from, to K, fn func(key K, value T, expires time.Time) bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var list []*item[K, T]
+
+	for key, it := range m.items {
+		if key >= from && key < to {
+			list = append(list, it)
+		}
+	}
+
+	slices.SortFunc(list, func(a, b *item[K, T]) int {
+		return cmp.Compare(a.Key, b.Key)
+	})
+
+	for _, it := range list {
+		if !fn(it.Key, it.Value, it.Expires) {
+			return
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package timestate_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mdigger/timestate"
+)
+
+func TestPrefix(t *testing.T) {
+	monitor := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+	for i, key := range []string{"eu/web/http", "eu/db/sql", "us/web/http", "eu/web/ssh"} {
+		monitor.Watch(key, i)
+	}
+
+	if keys := timestate.KeysWithPrefix(monitor, "eu/web/"); !slices.Equal(keys, []string{"eu/web/http", "eu/web/ssh"}) {
+		t.Errorf("Unexpected keys: %v", keys)
+	}
+
+	var keys []string
+	timestate.RangeByKey(monitor, "eu/", "eu0", func(key string, _ int, _ time.Time) bool {
+		keys = append(keys, key)
+
+		return true
+	})
+
+	if !slices.Equal(keys, []string{"eu/db/sql", "eu/web/http", "eu/web/ssh"}) {
+		t.Errorf("Unexpected range: %v", keys)
+	}
+
+	if n := timestate.RemovePrefix(monitor, "eu/"); n != 3 || monitor.Len() != 1 {
+		t.Errorf("Expected 3 removed states, got %d", n)
+	}
+}
@@ -0,0 +1,134 @@
+package timestate
+
+import "container/heap"
+
+// WatchWithPriority adds or updates a state like Watch and sets its
+// eviction priority. When the WithMaxSize limit is reached, the state with
+// the lowest priority is evicted first, breaking ties by the soonest
+// deadline. States added by other methods have priority 0. Natural
+// expiration is not affected by priorities.
+func (m *Monitor[K, T]) WatchWithPriority(key K, value T, prio int) bool {
+	now := m.clock.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	updated := m.watch(key, value, m.defaultTTL, m.expiresAt(now, m.defaultTTL), false)
+
+	it, tracked := m.items[key]
+	if !tracked {
+		return false // rejected by the size limit
+	}
+
+	if it.priority != prio {
+		it.priority = prio
+
+		if m.maxSize > 0 {
+			heap.Fix(&m.byPriority, it.pindex)
+		}
+	}
+
+	return updated
+}
+
+// evict removes the lowest-priority, soonest-to-expire state to make room
+// for a new one. Must be called with the lock held.
+func (m *Monitor[K, T]) evict() {
+	if m.byPriority.Len() == 0 {
+		return
+	}
+
+	it := heap.Pop(&m.byPriority).(*item[K, T]) //nolint:forcetypeassert
+	m.unschedule(it)
+	m.forget(it, ReasonEvicted)
+	m.stats.TotalEvicted++
+
+	select {
+	case m.evictedCh <- it.Key:
+	default: // no listener or channel full
+	}
+}
+
+// trim evicts states over the WithMaxSize limit in eviction order, e.g.
+// after loading entries. Must be called with the lock held.
+func (m *Monitor[K, T]) trim() {
+	for len(m.items) > m.maxSize && m.byPriority.Len() > 0 {
+		m.evict()
+	}
+}
+
+// priorityItems is a min-heap of items ordered by pinning, priority, last
+// use (with EvictLRU) and then by expiration time, so pinned states are
+// evicted last.
+type priorityItems[K comparable, T any] []*item[K, T]
+
+func (h priorityItems[K, T]) Len() int { return len(h) }
+func (h priorityItems[K, T]) Less(i, j int) bool {
+	if h[i].pinned != h[j].pinned {
+		return !h[i].pinned
+	}
+
+	if h[i].priority != h[j].priority {
+		return h[i].priority < h[j].priority
+	}
+
+	if h[i].used != h[j].used {
+		return h[i].used < h[j].used // EvictLRU only
+	}
+
+	return h[i].Expires.Before(h[j].Expires)
+}
+
+func (h priorityItems[K, T]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].pindex = i
+	h[j].pindex = j
+}
+
+func (h *priorityItems[K, T]) Push(x any) {
+	item := x.(*item[K, T]) //nolint:forcetypeassert
+	item.pindex = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *priorityItems[K, T]) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil // avoid memory leak
+	item.pindex = -1
+	*h = old[0 : n-1]
+
+	return item
+}
+
+var _ heap.Interface = (*priorityItems[any, any])(nil)
+
+// EvictionPolicy defines which state makes room for a new one when the
+// WithMaxSize limit is reached. Priorities set by WatchWithPriority and
+// pinning are respected by all policies.
+type EvictionPolicy int
+
+const (
+	// EvictSoonest evicts the state closest to its deadline.
+	EvictSoonest EvictionPolicy = iota
+	// EvictLRU evicts the state least recently watched.
+	EvictLRU
+	// RejectNew keeps the tracked states and does not add the new one.
+	RejectNew
+)
+
+// use records that the item was watched for EvictLRU.
+// Must be called with the lock held.
+func (m *Monitor[K, T]) use(it *item[K, T]) {
+	if m.eviction != EvictLRU || m.maxSize <= 0 {
+		return
+	}
+
+	m.useSeq++
+	it.used = m.useSeq
+
+	if it.pindex >= 0 && it.pindex < len(m.byPriority) && m.byPriority[it.pindex] == it {
+		heap.Fix(&m.byPriority, it.pindex)
+	}
+}
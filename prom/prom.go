@@ -0,0 +1,77 @@
+// Package prom exposes monitor statistics as a Prometheus collector. It is
+// a separate module, so the core package stays free of dependencies.
+package prom
+
+import (
+	"github.com/mdigger/timestate"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metric describes one exported value.
+type metric struct {
+	desc  *prometheus.Desc
+	kind  prometheus.ValueType
+	value func(timestate.Stats) float64
+}
+
+// collector reports the statistics returned by stats on every scrape.
+type collector struct {
+	stats    func() timestate.Stats
+	metrics  []metric
+	duration *prometheus.Desc
+}
+
+// Collector returns a collector of the statistics returned by stats,
+// usually the Stats method of a monitor, with metric names prefixed by
+// namespace (e.g. "timestate"). Register it with a prometheus.Registerer;
+// use prometheus.WrapRegistererWith to tell several monitors apart.
+func Collector(namespace string, stats func() timestate.Stats) prometheus.Collector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help, nil, nil)
+	}
+
+	return &collector{
+		stats: stats,
+		metrics: []metric{
+			{desc("states", "Currently tracked states."), prometheus.GaugeValue,
+				func(s timestate.Stats) float64 { return float64(s.Live) }},
+			{desc("watched_total", "New states added."), prometheus.CounterValue,
+				func(s timestate.Stats) float64 { return float64(s.TotalWatched) }},
+			{desc("changed_total", "Value changes of tracked states."), prometheus.CounterValue,
+				func(s timestate.Stats) float64 { return float64(s.TotalChanged) }},
+			{desc("expired_total", "States delivered as expired."), prometheus.CounterValue,
+				func(s timestate.Stats) float64 { return float64(s.TotalExpired) }},
+			{desc("removed_total", "States removed explicitly."), prometheus.CounterValue,
+				func(s timestate.Stats) float64 { return float64(s.TotalRemoved) }},
+			{desc("dropped_total", "Notifications requeued or discarded because the channel was full."),
+				prometheus.CounterValue,
+				func(s timestate.Stats) float64 { return float64(s.TotalDropped) }},
+			{desc("evicted_total", "States evicted by the size limit."), prometheus.CounterValue,
+				func(s timestate.Stats) float64 { return float64(s.TotalEvicted) }},
+			{desc("rejected_total", "New states rejected by the size limit."), prometheus.CounterValue,
+				func(s timestate.Stats) float64 { return float64(s.TotalRejected) }},
+		},
+		duration: desc("check_duration_seconds", "Time spent in expiration checks."),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, m := range c.metrics {
+		ch <- m.desc
+	}
+
+	ch <- c.duration
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stats()
+
+	for _, m := range c.metrics {
+		ch <- prometheus.MustNewConstMetric(m.desc, m.kind, m.value(s))
+	}
+
+	ch <- prometheus.MustNewConstSummary(c.duration,
+		s.TotalChecks, s.CheckDuration.Seconds(), nil)
+}
@@ -0,0 +1,45 @@
+package prom_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mdigger/timestate"
+	"github.com/mdigger/timestate/prom"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	monitor := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+	monitor.Watch("a", 1)
+	monitor.Watch("b", 1)
+	monitor.Remove("b")
+	monitor.Flush()
+
+	registry := prometheus.NewPedanticRegistry()
+	if err := registry.Register(prom.Collector("timestate", monitor.Stats)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `
+# HELP timestate_states Currently tracked states.
+# TYPE timestate_states gauge
+timestate_states 1
+# HELP timestate_watched_total New states added.
+# TYPE timestate_watched_total counter
+timestate_watched_total 2
+# HELP timestate_removed_total States removed explicitly.
+# TYPE timestate_removed_total counter
+timestate_removed_total 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(want),
+		"timestate_states", "timestate_watched_total", "timestate_removed_total"); err != nil {
+		t.Error(err)
+	}
+
+	if count, err := testutil.GatherAndCount(registry, "timestate_check_duration_seconds"); err != nil || count != 1 {
+		t.Errorf("Check duration summary: %d, %v", count, err)
+	}
+}
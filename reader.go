@@ -0,0 +1,37 @@
+package timestate
+
+import (
+	"iter"
+	"time"
+)
+
+// Reader is a read-only view of a Monitor, safe to share with code that
+// must not modify tracked states.
+type Reader[K comparable, T any] interface {
+	Get(key K) (value T, expires time.Time, exists bool)
+	Has(key K) bool
+	Stale(key K) bool
+	Len() int
+	Keys() []K
+	Snapshot() []Entry[K, T]
+	GetAll(keys []K) map[K]T
+	GetMeta(key K) (meta any, exists bool)
+	TTL(key K) (remaining time.Duration, exists bool)
+	RemainingTTLAll() map[K]time.Duration
+	PeekMin() (key K, value T, expires time.Time, ok bool)
+	NextExpiration() (key K, at time.Time, ok bool)
+	ExpiringWithin(d time.Duration) []K
+	ExpiringBefore(t time.Time) []K
+	ExpiringBetween(from, to time.Time) []K
+	All() iter.Seq2[K, T]
+	Range(fn func(key K, value T, expires time.Time) bool)
+	Stats() Stats
+	DefaultTTL() time.Duration
+}
+
+// Reader returns the monitor narrowed to its read-only methods.
+func (m *Monitor[K, T]) Reader() Reader[K, T] {
+	return m
+}
+
+var _ Reader[string, int] = (*Monitor[string, int])(nil)
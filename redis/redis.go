@@ -0,0 +1,220 @@
+// Package redis implements a state monitor shared by several processes on
+// top of Redis: deadlines are kept in a sorted set and values in a hash, so
+// all instances see the same states. Changes and expiration claims run as
+// Lua scripts, so each of them is atomic and an expiration is claimed by
+// one instance only.
+//
+// The package does not depend on a Redis client library: adapt the client
+// used by the application (e.g. github.com/redis/go-redis) to the Client
+// interface.
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// Client is the subset of Redis commands used by Monitor.
+type Client interface {
+	// ZScore returns the score of member (ZSCORE key member).
+	ZScore(ctx context.Context, key, member string) (score float64, ok bool, err error)
+	// HGet returns a hash field (HGET key field).
+	HGet(ctx context.Context, key, field string) (value string, ok bool, err error)
+	// Eval runs a Lua script returning an array of strings
+	// (EVAL script numkeys keys... args...).
+	Eval(ctx context.Context, script string, keys []string, args ...string) ([]string, error)
+}
+
+// watchScript stores the value in KEYS[2] and the deadline in KEYS[1]
+// unless the tracked value is the same, and returns the key if it did.
+const watchScript = `
+if redis.call('HGET', KEYS[2], ARGV[1]) == ARGV[3] then return {} end
+redis.call('ZADD', KEYS[1], ARGV[2], ARGV[1])
+redis.call('HSET', KEYS[2], ARGV[1], ARGV[3])
+return {ARGV[1]}`
+
+// removeScript deletes the state ARGV[1] and returns its key if it was
+// tracked.
+const removeScript = `
+local removed = redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('HDEL', KEYS[2], ARGV[1])
+if removed == 1 then return {ARGV[1]} end
+return {}`
+
+// popScript claims up to ARGV[2] states (all if not positive) with
+// deadlines up to ARGV[1] and returns key, deadline and value triples.
+// States without a value are dropped.
+const popScript = `
+local due
+if tonumber(ARGV[2]) > 0 then
+	due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'WITHSCORES', 'LIMIT', 0, ARGV[2])
+else
+	due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'WITHSCORES')
+end
+local claimed = {}
+for i = 1, #due, 2 do
+	local value = redis.call('HGET', KEYS[2], due[i])
+	redis.call('ZREM', KEYS[1], due[i])
+	redis.call('HDEL', KEYS[2], due[i])
+	if value then
+		table.insert(claimed, due[i])
+		table.insert(claimed, due[i + 1])
+		table.insert(claimed, value)
+	end
+end
+return claimed`
+
+// errReply is returned for a malformed script reply.
+var errReply = errors.New("redis: unexpected script reply")
+
+// Expired describes an expired state.
+type Expired[T any] struct {
+	Key     string    // Expired key
+	Value   T         // Last value
+	Expires time.Time // Deadline the state expired at
+}
+
+// Monitor tracks states with TTL expiration in Redis. Values are stored as
+// JSON and compared in that form.
+type Monitor[T comparable] struct {
+	client    Client
+	deadlines string // sorted set: key -> deadline in Unix milliseconds
+	values    string // hash: key -> JSON value
+	ttl       time.Duration
+}
+
+// New returns a monitor keeping its data under the name prefix, so several
+// monitors may share one Redis database.
+func New[T comparable](client Client, name string, ttl time.Duration) *Monitor[T] {
+	return &Monitor[T]{
+		client:    client,
+		deadlines: name + ":deadlines",
+		values:    name + ":values",
+		ttl:       ttl,
+	}
+}
+
+// Watch adds or updates a state only if the value changed, resetting its TTL.
+// The comparison and the update run as one script, so of concurrent
+// writers of the same value only one reports the change.
+// Returns true if the state was updated.
+func (m *Monitor[T]) Watch(ctx context.Context, key string, value T) (bool, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+
+	deadline := formatScore(time.Now().Add(m.ttl))
+
+	reply, err := m.client.Eval(ctx, watchScript, m.keys(), key, deadline, string(data))
+	if err != nil {
+		return false, err
+	}
+
+	return len(reply) > 0, nil
+}
+
+// Get returns the current value and deadline of a state.
+func (m *Monitor[T]) Get(ctx context.Context, key string) (value T, expires time.Time, exists bool, err error) {
+	s, ok, err := m.client.ZScore(ctx, m.deadlines, key)
+	if err != nil || !ok {
+		return value, expires, false, err
+	}
+
+	data, ok, err := m.client.HGet(ctx, m.values, key)
+	if err != nil || !ok {
+		return value, expires, false, err
+	}
+
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		return value, expires, false, err
+	}
+
+	return value, time.UnixMilli(int64(s)), true, nil
+}
+
+// Remove removes a state without expiration notification.
+// Returns false if the state was not tracked.
+func (m *Monitor[T]) Remove(ctx context.Context, key string) (bool, error) {
+	reply, err := m.client.Eval(ctx, removeScript, m.keys(), key)
+	if err != nil {
+		return false, err
+	}
+
+	return len(reply) > 0, nil
+}
+
+// PopExpired claims up to limit states expired by now (all if limit <= 0)
+// and returns them in deadline order. The claim is a single script that
+// checks the deadline, reads the value and deletes the state, so a state
+// is returned by one process only, and a state watched again with a later
+// deadline is not claimed. A claimed state is gone even if the caller
+// fails to handle it: delivery is at most once.
+func (m *Monitor[T]) PopExpired(ctx context.Context, now time.Time, limit int) ([]Expired[T], error) {
+	reply, err := m.client.Eval(ctx, popScript, m.keys(), formatScore(now), strconv.Itoa(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(reply)%3 != 0 {
+		return nil, errReply
+	}
+
+	expired := make([]Expired[T], 0, len(reply)/3)
+
+	for i := 0; i < len(reply); i += 3 {
+		ms, err := strconv.ParseFloat(reply[i+1], 64)
+		if err != nil {
+			return expired, err
+		}
+
+		e := Expired[T]{Key: reply[i], Expires: time.UnixMilli(int64(ms))}
+		if err := json.Unmarshal([]byte(reply[i+2]), &e.Value); err != nil {
+			return expired, err
+		}
+
+		expired = append(expired, e)
+	}
+
+	return expired, nil
+}
+
+// Run claims expired states every interval and sends them to ch until ctx
+// is done. Errors are passed to onError, which may be nil.
+func (m *Monitor[T]) Run(ctx context.Context, interval time.Duration, ch chan<- Expired[T], onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			expired, err := m.PopExpired(ctx, now, 0)
+			if err != nil && onError != nil {
+				onError(err)
+			}
+
+			for _, e := range expired {
+				select {
+				case ch <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// keys returns the Redis keys passed to the scripts.
+func (m *Monitor[T]) keys() []string {
+	return []string{m.deadlines, m.values}
+}
+
+// formatScore converts a deadline to a sorted set score.
+func formatScore(t time.Time) string {
+	return strconv.FormatInt(t.UnixMilli(), 10)
+}
@@ -0,0 +1,196 @@
+package redis_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/mdigger/timestate/redis"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// client adapts go-redis to Client.
+type client struct {
+	rdb *goredis.Client
+}
+
+// newClient returns a client of an in-memory Redis server, which runs the
+// Lua scripts like Redis does.
+func newClient(t *testing.T) client {
+	t.Helper()
+
+	rdb := goredis.NewClient(&goredis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	return client{rdb: rdb}
+}
+
+func (c client) ZScore(ctx context.Context, key, member string) (float64, bool, error) {
+	score, err := c.rdb.ZScore(ctx, key, member).Result()
+	if errors.Is(err, goredis.Nil) {
+		return 0, false, nil
+	}
+
+	return score, err == nil, err
+}
+
+func (c client) HGet(ctx context.Context, key, field string) (string, bool, error) {
+	value, err := c.rdb.HGet(ctx, key, field).Result()
+	if errors.Is(err, goredis.Nil) {
+		return "", false, nil
+	}
+
+	return value, err == nil, err
+}
+
+func (c client) Eval(ctx context.Context, script string, keys []string, args ...string) ([]string, error) {
+	argv := make([]any, len(args))
+	for i, arg := range args {
+		argv[i] = arg
+	}
+
+	return c.rdb.Eval(ctx, script, keys, argv...).StringSlice()
+}
+
+func TestMonitor(t *testing.T) {
+	ctx := t.Context()
+	client := newClient(t)
+	monitor := redis.New[int](client, "test", time.Minute)
+
+	if ok, err := monitor.Watch(ctx, "a", 1); !ok || err != nil {
+		t.Fatalf("Watch: %v, %v", ok, err)
+	}
+
+	if ok, _ := monitor.Watch(ctx, "a", 1); ok {
+		t.Error("Unchanged value reported as updated")
+	}
+
+	if value, _, exists, err := monitor.Get(ctx, "a"); !exists || value != 1 || err != nil {
+		t.Errorf("Get: %v, %v, %v", value, exists, err)
+	}
+
+	if removed, _ := monitor.Remove(ctx, "a"); !removed {
+		t.Error("State not removed")
+	}
+}
+
+func TestPopExpiredOnce(t *testing.T) {
+	ctx := t.Context()
+	client := newClient(t)
+	first := redis.New[int](client, "test", time.Millisecond)
+	second := redis.New[int](client, "test", time.Millisecond)
+
+	for _, key := range []string{"a", "b", "c"} {
+		if _, err := first.Watch(ctx, key, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Now().Add(time.Second)
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		total []string
+	)
+
+	for _, m := range []*redis.Monitor[int]{first, second} {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			expired, err := m.PopExpired(ctx, now, 0)
+			if err != nil {
+				t.Error(err)
+			}
+
+			mu.Lock()
+			for _, e := range expired {
+				total = append(total, e.Key)
+			}
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+	slices.Sort(total)
+
+	if !slices.Equal(total, []string{"a", "b", "c"}) {
+		t.Errorf("Expirations not claimed exactly once: %v", total)
+	}
+}
+
+func TestPopExpiredRewatched(t *testing.T) {
+	ctx := t.Context()
+	client := newClient(t)
+	short := redis.New[int](client, "test", time.Millisecond)
+	long := redis.New[int](client, "test", time.Hour)
+
+	if _, err := short.Watch(ctx, "a", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := short.Watch(ctx, "b", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := long.Watch(ctx, "a", 2); err != nil { // later deadline
+		t.Fatal(err)
+	}
+
+	expired, err := short.PopExpired(ctx, time.Now().Add(time.Second), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(expired) != 1 || expired[0].Key != "b" || expired[0].Value != 1 {
+		t.Errorf("Unexpected expirations: %+v", expired)
+	}
+
+	if value, _, exists, _ := long.Get(ctx, "a"); !exists || value != 2 {
+		t.Errorf("Watched state should stay, got %v, %v", value, exists)
+	}
+}
+
+func TestWatchConcurrent(t *testing.T) {
+	ctx := t.Context()
+	client := newClient(t)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		changes int
+	)
+
+	for range 10 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			m := redis.New[int](client, "test", time.Minute)
+
+			updated, err := m.Watch(ctx, "a", 1)
+			if err != nil {
+				t.Error(err)
+			}
+
+			if updated {
+				mu.Lock()
+				changes++
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if changes != 1 {
+		t.Errorf("Expected one writer to report the change, got %d", changes)
+	}
+}
@@ -0,0 +1,45 @@
+package timestate
+
+import (
+	"container/heap"
+	"time"
+)
+
+// WatchRepeating adds or updates a state like WatchWithTTL that is not
+// removed when it expires: after each notification it is scheduled again
+// interval later with its current value, turning the monitor into a
+// recurring reminder. Later updates of the value keep it repeating until
+// it is removed. A zero or negative interval watches the state once.
+// Returns true if the state was added or its value changed.
+func (m *Monitor[K, T]) WatchRepeating(key K, value T, interval time.Duration) bool {
+	now := m.clock.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	updated := m.watch(key, value, interval, m.expiresAt(now, interval), false)
+
+	if it, tracked := m.items[key]; tracked {
+		it.repeat = max(interval, 0)
+	}
+
+	return updated
+}
+
+// recur reports a repeating item as expired to hooks and events and
+// schedules it again. Must be called with the lock held.
+func (m *Monitor[K, T]) recur(it *item[K, T], now time.Time) {
+	m.emit(Event[K, T]{Key: it.Key, Value: it.Value, Reason: ReasonExpired})
+	m.hookForget(it, ReasonExpired)
+
+	it.Expires = m.expiresAt(now, it.repeat)
+	it.stale = false
+	m.armWarning(it)
+	heap.Push(&m.heap, it)
+	m.persist(it)
+	m.rearm(it)
+
+	if m.maxSize > 0 {
+		heap.Fix(&m.byPriority, it.pindex)
+	}
+}
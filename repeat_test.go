@@ -0,0 +1,76 @@
+package timestate_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mdigger/timestate"
+)
+
+func TestWatchRepeating(t *testing.T) {
+	clock := timestate.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	events := make(chan timestate.Expired[string, int], 10)
+	monitor := timestate.New(time.Second, time.Minute, nil,
+		timestate.WithClock[string, int](clock),
+		timestate.WithExpiredEvents[string, int](events),
+	)
+
+	monitor.WatchRepeating("reminder", 1, 10*time.Second)
+	monitor.Watch("once", 1)
+
+	for round, value := range []int{1, 2} {
+		clock.Advance(10 * time.Second)
+
+		if n := monitor.Flush(); n != 1 {
+			t.Fatalf("Round %d: expected 1 expiration, got %d", round, n)
+		}
+
+		if event := <-events; event.Key != "reminder" || event.Value != value {
+			t.Errorf("Round %d: unexpected event %+v", round, event)
+		}
+
+		_, expires, ok := monitor.Get("reminder")
+		if !ok || !expires.Equal(clock.Now().Add(10*time.Second)) {
+			t.Fatalf("Round %d: state should be scheduled again, got %v", round, expires)
+		}
+
+		monitor.WatchRepeating("reminder", 2, 10*time.Second)
+	}
+
+	monitor.Remove("reminder")
+	clock.Advance(time.Minute)
+
+	if n := monitor.Flush(); n != 1 {
+		t.Errorf("Only the one-time state should expire, got %d", n)
+	}
+
+	if monitor.Len() != 0 {
+		t.Errorf("Unexpected states left: %v", monitor.Keys())
+	}
+}
+
+func TestWatchRepeatingDebounce(t *testing.T) {
+	clock := timestate.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	expiredCh := make(chan string, 10)
+	monitor := timestate.New(time.Second, time.Minute, expiredCh,
+		timestate.WithClock[string, int](clock),
+		timestate.WithExpireDebounce[string, int](15*time.Second),
+	)
+
+	monitor.WatchRepeating("reminder", 1, 10*time.Second)
+
+	var delivered int
+
+	for range 4 {
+		clock.Advance(10 * time.Second)
+		delivered += monitor.Flush()
+
+		if !monitor.Has("reminder") {
+			t.Fatal("Debounced repeating state should stay scheduled")
+		}
+	}
+
+	if delivered != 2 {
+		t.Errorf("Expected every other cycle to be delivered, got %d", delivered)
+	}
+}
@@ -0,0 +1,164 @@
+package timestate
+
+import (
+	"context"
+	"hash/maphash"
+	"time"
+)
+
+// Sharded partitions keys across several independent monitors, each with
+// its own lock, heap and checker, so a check cycle only blocks the keys of
+// one shard. All shards share the expiration channel. Options are applied
+// to every shard, so limits such as WithMaxSize are per shard.
+type Sharded[K, T comparable] struct {
+	shards []*Monitor[K, T]
+	seed   maphash.Seed
+}
+
+// NewSharded creates n shards configured like New. Values of n below 1
+// are treated as 1. WithChangelog is not supported with more than one
+// shard: every shard numbers its records from 1, so records sent on one
+// channel would have overlapping sequence numbers.
+func NewSharded[K, T comparable](
+	n int,
+	checkInterval time.Duration,
+	defaultTTL time.Duration,
+	expiredCh chan<- K,
+	opts ...Option[K, T],
+) *Sharded[K, T] {
+	s := &Sharded[K, T]{
+		shards: make([]*Monitor[K, T], max(n, 1)),
+		seed:   maphash.MakeSeed(),
+	}
+
+	for i := range s.shards {
+		s.shards[i] = New(checkInterval, defaultTTL, expiredCh, opts...)
+	}
+
+	return s
+}
+
+// Shard returns the monitor responsible for the key, giving access to the
+// full Monitor API for that key.
+func (s *Sharded[K, T]) Shard(key K) *Monitor[K, T] {
+	if len(s.shards) == 1 {
+		return s.shards[0]
+	}
+
+	return s.shards[maphash.Comparable(s.seed, key)%uint64(len(s.shards))]
+}
+
+// Watch adds or updates a state like Monitor.Watch.
+func (s *Sharded[K, T]) Watch(key K, value T) bool {
+	return s.Shard(key).Watch(key, value)
+}
+
+// WatchWithTTL adds or updates a state like Monitor.WatchWithTTL.
+func (s *Sharded[K, T]) WatchWithTTL(key K, value T, ttl time.Duration) bool {
+	return s.Shard(key).WatchWithTTL(key, value, ttl)
+}
+
+// WatchUntil adds or updates a state like Monitor.WatchUntil.
+func (s *Sharded[K, T]) WatchUntil(key K, value T, deadline time.Time) bool {
+	return s.Shard(key).WatchUntil(key, value, deadline)
+}
+
+// Touch resets the TTL of a state like Monitor.Touch.
+func (s *Sharded[K, T]) Touch(key K) bool {
+	return s.Shard(key).Touch(key)
+}
+
+// Get retrieves a state like Monitor.Get.
+func (s *Sharded[K, T]) Get(key K) (value T, expires time.Time, exists bool) {
+	return s.Shard(key).Get(key)
+}
+
+// Has reports whether a state is tracked for the key.
+func (s *Sharded[K, T]) Has(key K) bool {
+	return s.Shard(key).Has(key)
+}
+
+// Remove removes a state like Monitor.Remove.
+func (s *Sharded[K, T]) Remove(key K) bool {
+	return s.Shard(key).Remove(key)
+}
+
+// Len returns the number of tracked states in all shards.
+func (s *Sharded[K, T]) Len() int {
+	var n int
+	for _, m := range s.shards {
+		n += m.Len()
+	}
+
+	return n
+}
+
+// Range calls fn for each live state like Monitor.Range, shard by shard,
+// until fn returns false. Each shard is locked only while it is visited.
+func (s *Sharded[K, T]) Range(fn func(key K, value T, expires time.Time) bool) {
+	for _, m := range s.shards {
+		stopped := false
+
+		m.Range(func(key K, value T, expires time.Time) bool {
+			stopped = !fn(key, value, expires)
+
+			return !stopped
+		})
+
+		if stopped {
+			return
+		}
+	}
+}
+
+// Stats returns the counters summed over all shards.
+func (s *Sharded[K, T]) Stats() Stats {
+	var total Stats
+
+	for _, m := range s.shards {
+		st := m.Stats()
+		total.Live += st.Live
+		total.HeapSize += st.HeapSize
+		total.TotalWatched += st.TotalWatched
+		total.TotalChanged += st.TotalChanged
+		total.TotalExpired += st.TotalExpired
+		total.TotalRemoved += st.TotalRemoved
+		total.TotalDropped += st.TotalDropped
+		total.TotalEvicted += st.TotalEvicted
+		total.TotalRejected += st.TotalRejected
+		total.TotalChecks += st.TotalChecks
+		total.CheckDuration += st.CheckDuration
+	}
+
+	return total
+}
+
+// Start begins monitoring of every shard in its own goroutine.
+// Stop by canceling the context or with Stop.
+func (s *Sharded[K, T]) Start(ctx context.Context) {
+	for _, m := range s.shards {
+		m.Start(ctx)
+	}
+}
+
+// Stop stops the checkers of all shards and waits until they exit.
+func (s *Sharded[K, T]) Stop() {
+	for _, m := range s.shards {
+		m.Stop()
+	}
+}
+
+// Shutdown stops all shards and delivers their already expired states
+// like Monitor.Shutdown. Returns the ctx error if some notifications were
+// not delivered.
+func (s *Sharded[K, T]) Shutdown(ctx context.Context) error {
+	s.Stop()
+
+	for _, m := range s.shards {
+		if err := m.Shutdown(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
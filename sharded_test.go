@@ -0,0 +1,72 @@
+package timestate_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mdigger/timestate"
+)
+
+func TestSharded(t *testing.T) {
+	expiredCh := make(chan string, 100)
+	sharded := timestate.NewSharded[string, int](4, 10*time.Millisecond, time.Hour, expiredCh)
+
+	for i := range 50 {
+		key := fmt.Sprint("key", i)
+		if !sharded.Watch(key, i) {
+			t.Fatalf("Watch %s should add the state", key)
+		}
+
+		if sharded.Shard(key) != sharded.Shard(key) {
+			t.Fatalf("Key %s should always map to the same shard", key)
+		}
+	}
+
+	if sharded.Len() != 50 {
+		t.Errorf("Expected 50 states, got %d", sharded.Len())
+	}
+
+	if value, _, ok := sharded.Get("key7"); !ok || value != 7 {
+		t.Errorf("Unexpected value: %d, %v", value, ok)
+	}
+
+	if !sharded.Remove("key7") || sharded.Has("key7") {
+		t.Error("Remove should delete the state")
+	}
+
+	count := 0
+	sharded.Range(func(string, int, time.Time) bool {
+		count++
+
+		return count < 10
+	})
+
+	if count != 10 {
+		t.Errorf("Range should stop after 10 states, visited %d", count)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	sharded.Start(ctx)
+	sharded.WatchWithTTL("short", 1, 20*time.Millisecond)
+
+	select {
+	case key := <-expiredCh:
+		if key != "short" {
+			t.Errorf("Expected short, got %s", key)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("State did not expire")
+	}
+
+	if err := sharded.Shutdown(t.Context()); err != nil {
+		t.Errorf("Shutdown failed: %v", err)
+	}
+
+	if stats := sharded.Stats(); stats.Live != 49 || stats.TotalExpired != 1 || stats.TotalRemoved != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
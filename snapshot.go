@@ -0,0 +1,169 @@
+package timestate
+
+import (
+	"container/heap"
+	"time"
+)
+
+// Entry is a copy of a tracked state.
+type Entry[K comparable, T any] struct {
+	Key      K         `json:"key"`
+	Value    T         `json:"value"`
+	Expires  time.Time `json:"expires"`
+	Absolute bool      `json:"absolute,omitempty"` // Added with WatchUntil
+	Pinned   bool      `json:"pinned,omitempty"`   // Excluded from expiration
+}
+
+// Snapshot returns copies of all tracked states in no particular order.
+// Metadata attached with WatchWithMeta is not included.
+func (m *Monitor[K, T]) Snapshot() []Entry[K, T] {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]Entry[K, T], 0, len(m.items))
+	for _, it := range m.items {
+		list = append(list, it.entry())
+	}
+
+	return list
+}
+
+// Restore creates a Monitor preloaded with entries, typically returned by
+// Snapshot, keeping their original deadlines. Entries already past their
+// deadline are delivered as expired by the first check. Parameters are
+// the same as for New.
+func Restore[K, T comparable](
+	checkInterval time.Duration,
+	defaultTTL time.Duration,
+	expiredCh chan<- K,
+	entries []Entry[K, T],
+	opts ...Option[K, T],
+) *Monitor[K, T] {
+	m := New(checkInterval, defaultTTL, expiredCh, opts...)
+	m.load(entries)
+
+	return m
+}
+
+// Restore replaces all tracked states of a running or new monitor with
+// entries, typically returned by Snapshot, keeping their original
+// deadlines. Replaced states are dropped without expiration notifications.
+// Entries already past their deadline are delivered as expired by the next
+// check, so states that will not be reported again still expire once.
+// States over the WithMaxSize limit are evicted in eviction order, whatever
+// the policy, and reported like other evictions.
+func (m *Monitor[K, T]) Restore(entries []Entry[K, T]) {
+	m.mu.Lock()
+	m.load(entries)
+	m.mu.Unlock()
+}
+
+// load replaces all tracked states with entries and rebuilds the heap.
+// Must be called with the lock held.
+func (m *Monitor[K, T]) load(entries []Entry[K, T]) {
+	for _, it := range m.items {
+		m.forget(it, ReasonRemoved)
+	}
+
+	m.heap = make(items[K, T], 0, len(entries))
+	m.items = make(map[K]*item[K, T], len(entries))
+
+	for _, entry := range entries {
+		m.items[entry.Key] = m.entryItem(entry) // duplicate keys: last one wins
+	}
+
+	for _, it := range m.items {
+		if it.pinned {
+			it.Expires = time.Time{}
+
+			continue
+		}
+
+		m.armWarning(it)
+		it.index = len(m.heap)
+		m.heap = append(m.heap, it)
+	}
+
+	for _, it := range m.items {
+		m.persist(it)
+		m.hookAdd(it)
+	}
+
+	heap.Init(&m.heap)
+
+	if m.heap.Len() > 0 {
+		m.rearm(m.heap[0])
+	}
+
+	if m.maxSize > 0 {
+		m.byPriority = make(priorityItems[K, T], 0, len(m.items))
+		for _, it := range m.items {
+			it.pindex = len(m.byPriority)
+			m.byPriority = append(m.byPriority, it)
+		}
+
+		heap.Init(&m.byPriority)
+		m.trim()
+	}
+}
+
+// loadMore adds entries to the tracked states like load, replacing states
+// with the same keys and keeping the others. Must be called with the lock
+// held.
+func (m *Monitor[K, T]) loadMore(entries []Entry[K, T]) {
+	for _, entry := range entries {
+		if old, exists := m.items[entry.Key]; exists {
+			m.unschedule(old)
+			m.forget(old, ReasonRemoved)
+		}
+
+		it := m.entryItem(entry)
+		m.items[it.Key] = it
+
+		if it.pinned {
+			it.Expires = time.Time{}
+		} else {
+			m.armWarning(it)
+			heap.Push(&m.heap, it)
+			m.rearm(it)
+		}
+
+		m.persist(it)
+		m.hookAdd(it)
+
+		if m.maxSize > 0 {
+			heap.Push(&m.byPriority, it)
+			m.trim()
+		}
+	}
+}
+
+// entryItem returns a new unscheduled item for the entry.
+func (m *Monitor[K, T]) entryItem(entry Entry[K, T]) *item[K, T] {
+	it := &item[K, T]{
+		Key:      entry.Key,
+		Expires:  entry.Expires,
+		index:    -1,
+		pindex:   -1,
+		absolute: entry.Absolute,
+		pinned:   entry.Pinned,
+	}
+	m.set(it, entry.Value)
+
+	if !it.absolute {
+		it.ttl = m.defaultTTL
+	}
+
+	return it
+}
+
+// entry returns a copy of the item state.
+func (it *item[K, T]) entry() Entry[K, T] {
+	return Entry[K, T]{
+		Key:      it.Key,
+		Value:    it.Value,
+		Expires:  it.Expires,
+		Absolute: it.absolute,
+		Pinned:   it.pinned,
+	}
+}
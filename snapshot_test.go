@@ -0,0 +1,177 @@
+package timestate_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mdigger/timestate"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	src := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+	src.Watch("a", 1)
+	src.WatchWithTTL("b", 2, time.Hour)
+	src.WatchUntil("c", 3, time.Now().Add(time.Hour))
+
+	expiredCh := make(chan string, 1)
+	dst := timestate.Restore(time.Second, time.Minute, expiredCh, src.Snapshot())
+
+	for _, entry := range src.Snapshot() {
+		val, expires, exists := dst.Get(entry.Key)
+		if !exists || val != entry.Value || !expires.Equal(entry.Expires) {
+			t.Errorf("State %q mismatch: got %v %v, want %v %v",
+				entry.Key, val, expires, entry.Value, entry.Expires)
+		}
+	}
+
+	if stats := dst.Stats(); stats.Live != 3 {
+		t.Errorf("Unexpected live count: %d", stats.Live)
+	}
+}
+
+func TestRestorePastDue(t *testing.T) {
+	expiredCh := make(chan string, 1)
+	monitor := timestate.Restore(20*time.Millisecond, time.Minute, expiredCh,
+		[]timestate.Entry[string, int]{
+			{Key: "old", Value: 1, Expires: time.Now().Add(-time.Hour)},
+		})
+	ctx := t.Context()
+	monitor.Start(ctx)
+
+	select {
+	case key := <-expiredCh:
+		if key != "old" {
+			t.Errorf("Unexpected expired ID: %s", key)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Error("Past-due entry did not expire")
+	}
+}
+
+func TestRestoreMethod(t *testing.T) {
+	monitor := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+	monitor.Watch("stale", 1)
+
+	deadline := time.Now().Add(time.Hour)
+	monitor.Restore([]timestate.Entry[string, int]{
+		{Key: "a", Value: 1, Expires: deadline},
+	})
+
+	if monitor.Has("stale") {
+		t.Error("Previous state was not replaced")
+	}
+
+	if val, expires, exists := monitor.Get("a"); !exists || val != 1 || !expires.Equal(deadline) {
+		t.Errorf("Unexpected restored state: %v %v %v", val, expires, exists)
+	}
+}
+
+func TestRestoreMaxSize(t *testing.T) {
+	evictedCh := make(chan string, 4)
+	monitor := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1),
+		timestate.WithMaxSize[string, int](2),
+		timestate.WithEvictedChannel[string, int](evictedCh),
+	)
+
+	now := time.Now()
+	monitor.Restore([]timestate.Entry[string, int]{
+		{Key: "a", Value: 1, Expires: now.Add(time.Minute)},
+		{Key: "b", Value: 1, Expires: now.Add(2 * time.Minute)},
+		{Key: "c", Value: 1, Expires: now.Add(3 * time.Minute)},
+		{Key: "d", Value: 1, Expires: now.Add(4 * time.Minute)},
+	})
+
+	if monitor.Len() != 2 || !monitor.Has("c") || !monitor.Has("d") {
+		t.Errorf("Expected the 2 latest states, got %d", monitor.Len())
+	}
+
+	if len(evictedCh) != 2 {
+		t.Errorf("Expected 2 evictions, got %d", len(evictedCh))
+	}
+
+	monitor.Watch("e", 1)
+
+	if monitor.Len() != 2 {
+		t.Errorf("Size limit exceeded after Watch: %d", monitor.Len())
+	}
+
+	checkInvariants(t, monitor)
+}
+
+func TestGobRoundTrip(t *testing.T) {
+	src := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+	for i := range 3000 { // several ReadFrom batches
+		src.WatchWithTTL(fmt.Sprint(i), i, time.Duration(i+1)*time.Second)
+	}
+
+	src.WatchUntil("fixed", 1, time.Now().Add(time.Hour))
+	src.Pin("0")
+
+	var buf bytes.Buffer
+
+	written, err := src.WriteTo(&buf)
+	if err != nil || written != int64(buf.Len()) {
+		t.Fatalf("WriteTo: %d bytes, %v", written, err)
+	}
+
+	dst := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+	if read, err := dst.ReadFrom(&buf); err != nil || read != written {
+		t.Fatalf("ReadFrom: %d bytes, %v", read, err)
+	}
+
+	for _, entry := range src.Snapshot() {
+		val, expires, exists := dst.Get(entry.Key)
+		if !exists || val != entry.Value || !expires.Equal(entry.Expires) {
+			t.Errorf("State %q mismatch", entry.Key)
+		}
+	}
+
+	if dst.Len() != src.Len() {
+		t.Errorf("Expected %d states, got %d", src.Len(), dst.Len())
+	}
+
+	if key, _, _ := dst.NextExpiration(); key != "1" {
+		t.Errorf("Heap order not restored, next is %q", key)
+	}
+
+	checkInvariants(t, dst)
+}
+
+func TestGobCorruptStream(t *testing.T) {
+	src := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+	for i := range 3000 {
+		src.Watch(fmt.Sprint(i), i)
+	}
+
+	var buf bytes.Buffer
+	if _, err := src.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buf.Bytes()
+
+	dst := timestate.New[string, int](time.Second, time.Minute, make(chan string, 1))
+	dst.Watch("old", 1)
+
+	// broken within the first batch: nothing is replaced
+	if _, err := dst.ReadFrom(bytes.NewReader(data[:100])); err == nil {
+		t.Fatal("Expected an error for a truncated stream")
+	}
+
+	if !dst.Has("old") || dst.Len() != 1 {
+		t.Errorf("Monitor changed by a failed read: %d states", dst.Len())
+	}
+
+	// broken later: only the decoded entries are left
+	if _, err := dst.ReadFrom(bytes.NewReader(data[:len(data)-10])); err == nil {
+		t.Fatal("Expected an error for a truncated stream")
+	}
+
+	if dst.Has("old") || dst.Len() < 1024 || dst.Len() >= 3000 {
+		t.Errorf("Unexpected states after a late error: %d, old %v", dst.Len(), dst.Has("old"))
+	}
+
+	checkInvariants(t, dst)
+}
@@ -0,0 +1,81 @@
+package timestate
+
+import (
+	"expvar"
+	"time"
+)
+
+// Stats holds cumulative monitor counters.
+// All totals only grow during the lifetime of the monitor.
+type Stats struct {
+	Live          int           // Currently tracked states
+	HeapSize      int           // States scheduled in the expiration heap (not pinned)
+	TotalWatched  uint64        // New states added
+	TotalChanged  uint64        // Value changes of tracked states
+	TotalExpired  uint64        // States delivered as expired
+	TotalRemoved  uint64        // States removed explicitly
+	TotalDropped  uint64        // Notifications requeued or discarded because the channel was full
+	TotalEvicted  uint64        // States evicted by the size limit
+	TotalRejected uint64        // New states rejected by the size limit
+	TotalChecks   uint64        // Completed expiration checks
+	CheckDuration time.Duration // Total time spent in checks, including delivery
+}
+
+// Stats returns a consistent copy of the monitor counters.
+func (m *Monitor[K, T]) Stats() Stats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats := m.stats
+	stats.Live = len(m.items)
+	stats.HeapSize = len(m.heap)
+
+	return stats
+}
+
+// PublishStats publishes the monitor statistics with expvar under name,
+// so they are served by the /debug/vars handler. Like expvar.Publish, it
+// panics if the name is already in use: published variables cannot be
+// removed, so publish each monitor once per process under its own name.
+func (m *Monitor[K, T]) PublishStats(name string) {
+	expvar.Publish(name, expvar.Func(func() any { return m.Stats() }))
+}
+
+// Timing describes how late expirations are delivered relative to their
+// deadlines. Consistently high lateness means the check interval is too
+// coarse.
+type Timing struct {
+	Count   uint64        // Measured expirations
+	Max     time.Duration // Maximum lateness
+	Average time.Duration // Average lateness
+}
+
+// timing accumulates expiration lateness.
+type timing struct {
+	count uint64
+	max   time.Duration
+	total time.Duration
+}
+
+func (t *timing) record(late time.Duration) {
+	t.count++
+	t.total += late
+	t.max = max(t.max, late)
+}
+
+// Timing returns expiration lateness diagnostics.
+// Returns zero values unless WithTimingDiagnostics is set.
+func (m *Monitor[K, T]) Timing() Timing {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.timing == nil || m.timing.count == 0 {
+		return Timing{}
+	}
+
+	return Timing{
+		Count:   m.timing.count,
+		Max:     m.timing.max,
+		Average: m.timing.total / time.Duration(m.timing.count),
+	}
+}
@@ -0,0 +1,102 @@
+package timestate
+
+import "slices"
+
+// subscriberBuffer is the channel capacity of each subscriber.
+const subscriberBuffer = 64
+
+// Subscribe returns a new channel that receives a copy of every expiration
+// delivered after the call, in addition to the channel passed to New.
+// Each subscriber has its own buffer; when it is full, notifications for
+// that subscriber are dropped so one slow consumer never delays the others.
+// Call the returned cancel function to unsubscribe and close the channel.
+func (m *Monitor[K, T]) Subscribe() (<-chan K, func()) {
+	ch := make(chan K, subscriberBuffer)
+
+	m.mu.Lock()
+	if m.subscribers == nil {
+		m.subscribers = make(map[chan K]struct{})
+	}
+
+	m.subscribers[ch] = struct{}{}
+	m.mu.Unlock()
+
+	cancel := func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		if _, ok := m.subscribers[ch]; ok {
+			delete(m.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return ch, cancel
+}
+
+// eventSub is a subscriber of SubscribeEvents.
+type eventSub[K comparable, T any] struct {
+	ch    chan Event[K, T]
+	pred  func(K, T) bool // nil - all events
+	group string          // empty - all groups
+}
+
+// SubscribeEvents is like Subscribe, but the channel receives expiration
+// events with the last value and has room for buffer events (at least one).
+func (m *Monitor[K, T]) SubscribeEvents(buffer int) (<-chan Event[K, T], func()) {
+	return m.subscribeEvents(buffer, nil, "")
+}
+
+// SubscribeFunc is like SubscribeEvents, but the channel only receives
+// expirations for which pred returns true. The predicate is called with
+// the lock held, so it must be fast and must not use the monitor.
+func (m *Monitor[K, T]) SubscribeFunc(pred func(key K, value T) bool) (<-chan Event[K, T], func()) {
+	return m.subscribeEvents(subscriberBuffer, pred, "")
+}
+
+// subscribeEvents registers an event subscriber.
+func (m *Monitor[K, T]) subscribeEvents(buffer int, pred func(K, T) bool, group string) (<-chan Event[K, T], func()) {
+	sub := &eventSub[K, T]{ch: make(chan Event[K, T], max(buffer, 1)), pred: pred, group: group}
+
+	m.mu.Lock()
+	m.eventSubs = append(m.eventSubs, sub)
+	m.mu.Unlock()
+
+	cancel := func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		if i := slices.Index(m.eventSubs, sub); i >= 0 {
+			m.eventSubs = slices.Delete(m.eventSubs, i, i+1)
+			close(sub.ch)
+		}
+	}
+
+	return sub.ch, cancel
+}
+
+// broadcast sends the expired item to all subscribers without blocking.
+// Must be called with the lock held.
+func (m *Monitor[K, T]) broadcast(it *item[K, T]) {
+	for ch := range m.subscribers {
+		select {
+		case ch <- it.Key:
+		default: // slow subscriber
+		}
+	}
+
+	for _, sub := range m.eventSubs {
+		if sub.group != "" && sub.group != it.group {
+			continue
+		}
+
+		if sub.pred != nil && !sub.pred(it.Key, it.Value) {
+			continue
+		}
+
+		select {
+		case sub.ch <- Event[K, T]{Key: it.Key, Value: it.Value, Reason: ReasonExpired}:
+		default: // slow subscriber
+		}
+	}
+}
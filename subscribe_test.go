@@ -0,0 +1,104 @@
+package timestate_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mdigger/timestate"
+)
+
+func TestSubscribe(t *testing.T) {
+	expiredCh := make(chan string, 10)
+	monitor := timestate.New[string, int](20*time.Millisecond, 10*time.Millisecond, expiredCh)
+	ctx := t.Context()
+	monitor.Start(ctx)
+
+	sub1, cancel1 := monitor.Subscribe()
+	sub2, cancel2 := monitor.Subscribe()
+	defer cancel2()
+
+	monitor.Watch("key", 1)
+
+	for _, ch := range []<-chan string{expiredCh, sub1, sub2} {
+		select {
+		case key := <-ch:
+			if key != "key" {
+				t.Errorf("Unexpected expired ID: %s", key)
+			}
+		case <-time.After(500 * time.Millisecond):
+			t.Fatal("Expiration was not delivered")
+		}
+	}
+
+	cancel1()
+	cancel1() // safe to call twice
+
+	if _, ok := <-sub1; ok {
+		t.Error("Channel should be closed after cancel")
+	}
+}
+
+func TestSubscribeEvents(t *testing.T) {
+	clock := timestate.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	monitor := timestate.New(time.Second, time.Minute, make(chan string, 10),
+		timestate.WithClock[string, int](clock),
+	)
+
+	sub1, cancel1 := monitor.SubscribeEvents(1)
+	sub2, cancel2 := monitor.SubscribeEvents(10)
+	defer cancel2()
+
+	monitor.WatchWithTTL("a", 1, time.Second)
+	monitor.WatchWithTTL("b", 2, 2*time.Second)
+	clock.Advance(time.Minute)
+	monitor.Flush()
+
+	want := timestate.Event[string, int]{Key: "a", Value: 1, Reason: timestate.ReasonExpired}
+	if event := <-sub1; event != want {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+
+	select {
+	case event := <-sub1:
+		t.Errorf("Full subscriber should drop events, got %+v", event)
+	default:
+	}
+
+	if len(sub2) != 2 {
+		t.Errorf("Each subscriber should receive all events, got %d", len(sub2))
+	}
+
+	cancel1()
+	cancel1() // safe to call twice
+
+	if _, ok := <-sub1; ok {
+		t.Error("Channel should be closed after cancel")
+	}
+}
+
+func TestSubscribeFunc(t *testing.T) {
+	clock := timestate.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	monitor := timestate.New(time.Second, time.Minute, make(chan string, 10),
+		timestate.WithClock[string, int](clock),
+	)
+
+	critical, cancel := monitor.SubscribeFunc(func(key string, value int) bool {
+		return strings.HasPrefix(key, "db/") && value > 90
+	})
+	defer cancel()
+
+	monitor.Watch("db/cpu", 95)
+	monitor.Watch("db/mem", 50)
+	monitor.Watch("web/cpu", 99)
+	clock.Advance(time.Minute)
+	monitor.Flush()
+
+	if len(critical) != 1 {
+		t.Fatalf("Expected 1 matching event, got %d", len(critical))
+	}
+
+	if event := <-critical; event.Key != "db/cpu" || event.Value != 95 {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+}
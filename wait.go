@@ -0,0 +1,66 @@
+package timestate
+
+import (
+	"context"
+	"errors"
+	"slices"
+)
+
+// ErrNotFound is returned by WaitFor when the key is not tracked or stops
+// being tracked without expiring (e.g., removed or evicted).
+var ErrNotFound = errors.New("timestate: key not found")
+
+// WaitFor blocks until the state for key expires and returns nil.
+// Returns ctx.Err() if ctx is done first, or ErrNotFound if the key is not
+// tracked or is removed before it expires.
+func (m *Monitor[K, T]) WaitFor(ctx context.Context, key K) error {
+	done := make(chan error, 1)
+
+	m.mu.Lock()
+	if _, ok := m.items[key]; !ok {
+		m.mu.Unlock()
+
+		return ErrNotFound
+	}
+
+	if m.waiters == nil {
+		m.waiters = make(map[K][]chan error)
+	}
+
+	m.waiters[key] = append(m.waiters[key], done)
+	m.mu.Unlock()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		m.mu.Lock()
+		m.unwait(key, done)
+		m.mu.Unlock()
+
+		return ctx.Err()
+	}
+}
+
+// wake notifies all WaitFor callers of the key with err.
+// Must be called with the lock held.
+func (m *Monitor[K, T]) wake(key K, err error) {
+	for _, done := range m.waiters[key] {
+		done <- err // buffered, never blocks
+	}
+
+	delete(m.waiters, key)
+}
+
+// unwait removes a WaitFor subscription. Must be called with the lock held.
+func (m *Monitor[K, T]) unwait(key K, done chan error) {
+	list := slices.DeleteFunc(m.waiters[key], func(ch chan error) bool {
+		return ch == done
+	})
+
+	if len(list) == 0 {
+		delete(m.waiters, key)
+	} else {
+		m.waiters[key] = list
+	}
+}
@@ -0,0 +1,42 @@
+package timestate_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mdigger/timestate"
+)
+
+func TestWaitFor(t *testing.T) {
+	expiredCh := make(chan string, 10)
+	monitor := timestate.New[string, int](10*time.Millisecond, time.Minute, expiredCh)
+	ctx := t.Context()
+	monitor.Start(ctx)
+
+	if err := monitor.WaitFor(ctx, "missing"); !errors.Is(err, timestate.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	monitor.WatchWithTTL("expiring", 1, 20*time.Millisecond)
+	if err := monitor.WaitFor(ctx, "expiring"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	monitor.Watch("removed", 1)
+	time.AfterFunc(20*time.Millisecond, func() { monitor.Remove("removed") })
+
+	if err := monitor.WaitFor(ctx, "removed"); !errors.Is(err, timestate.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for removed key, got %v", err)
+	}
+
+	monitor.Watch("long", 1)
+
+	waitCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+
+	if err := monitor.WaitFor(waitCtx, "long"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context error, got %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package timestate
+
+import (
+	"container/heap"
+	"time"
+)
+
+// armWarning schedules a warning for the item when its remaining lifetime
+// crosses the configured threshold. Must be called with the lock held.
+func (m *Monitor[K, T]) armWarning(it *item[K, T]) {
+	if m.warnCh == nil && m.warnFunc == nil {
+		return
+	}
+
+	now := m.clock.Now()
+	lifetime := it.Expires.Sub(now)
+	armed := !it.warnAt.IsZero()
+	it.warnAt = now.Add(time.Duration(m.warnRatio * float64(lifetime)))
+
+	if armed {
+		heap.Fix(&m.warnings, it.windex)
+	} else {
+		heap.Push(&m.warnings, it)
+	}
+}
+
+// disarm cancels the pending warning of the item, if any.
+// Must be called with the lock held.
+func (m *Monitor[K, T]) disarm(it *item[K, T]) {
+	if it.warnAt.IsZero() {
+		return
+	}
+
+	heap.Remove(&m.warnings, it.windex)
+	it.warnAt = time.Time{}
+}
+
+// warn sends warnings for items that crossed their threshold by now and
+// returns copies of them for the warning callback. Only the due warnings
+// are visited, in the order of their times.
+// Must be called with the lock held.
+func (m *Monitor[K, T]) warn(now time.Time) (warned []Entry[K, T]) {
+	for len(m.warnings) > 0 {
+		it := m.warnings[0]
+		if it.warnAt.After(now) {
+			break
+		}
+
+		if !it.Expires.After(now) {
+			m.disarm(it) // expires first
+
+			continue
+		}
+
+		if m.warnCh != nil {
+			select {
+			case m.warnCh <- it.Key:
+			default:
+				return warned // retry on the next check
+			}
+		}
+
+		m.disarm(it) // once per lifetime
+
+		if m.warnFunc != nil {
+			warned = append(warned, Entry[K, T]{
+				Key:      it.Key,
+				Value:    it.Value,
+				Expires:  it.Expires,
+				Absolute: it.absolute,
+			})
+		}
+	}
+
+	return warned
+}
+
+// warnItems is a min-heap of items with pending warnings ordered by the
+// warning time. An item is in it while its warnAt is set.
+type warnItems[K comparable, T any] []*item[K, T]
+
+func (h warnItems[K, T]) Len() int { return len(h) }
+func (h warnItems[K, T]) Less(i, j int) bool {
+	return h[i].warnAt.Before(h[j].warnAt)
+}
+
+func (h warnItems[K, T]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].windex = i
+	h[j].windex = j
+}
+
+func (h *warnItems[K, T]) Push(x any) {
+	item := x.(*item[K, T]) //nolint:forcetypeassert
+	item.windex = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *warnItems[K, T]) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil // avoid memory leak
+	*h = old[0 : n-1]
+
+	return item
+}
+
+var _ heap.Interface = (*warnItems[any, any])(nil)
@@ -0,0 +1,125 @@
+package timestate_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mdigger/timestate"
+)
+
+func TestWarnThreshold(t *testing.T) {
+	expiredCh := make(chan string, 1)
+	warnCh := make(chan string, 1)
+	monitor := timestate.New(10*time.Millisecond, 200*time.Millisecond, expiredCh,
+		timestate.WithWarnThreshold[string, int](0.5, warnCh),
+	)
+	ctx := t.Context()
+	monitor.Start(ctx)
+
+	monitor.Watch("key", 1)
+
+	select {
+	case key := <-warnCh:
+		if key != "key" {
+			t.Errorf("Unexpected warned ID: %s", key)
+		}
+	case <-expiredCh:
+		t.Fatal("Expiration came before warning")
+	case <-time.After(time.Second):
+		t.Fatal("Warning was not sent")
+	}
+
+	// refresh re-arms the warning
+	monitor.Watch("key", 2)
+
+	select {
+	case <-warnCh:
+	case <-expiredCh:
+		t.Fatal("Expiration came before re-armed warning")
+	case <-time.After(time.Second):
+		t.Fatal("Warning was not re-armed")
+	}
+
+	select {
+	case <-expiredCh:
+	case <-warnCh:
+		t.Fatal("Warning sent twice")
+	case <-time.After(time.Second):
+		t.Fatal("State did not expire as expected")
+	}
+}
+
+func TestWarnFunc(t *testing.T) {
+	expiredCh := make(chan string, 1)
+	warned := make(chan int, 10)
+	monitor := timestate.New(10*time.Millisecond, 200*time.Millisecond, expiredCh,
+		timestate.WithWarnFunc(0.5, func(_ string, value int) { warned <- value }),
+	)
+	ctx := t.Context()
+	monitor.Start(ctx)
+
+	monitor.Watch("key", 7)
+
+	select {
+	case value := <-warned:
+		if value != 7 {
+			t.Errorf("Unexpected warned value: %d", value)
+		}
+	case <-expiredCh:
+		t.Fatal("Expiration came before warning")
+	case <-time.After(time.Second):
+		t.Fatal("Warning callback was not called")
+	}
+
+	select {
+	case <-expiredCh:
+	case <-warned:
+		t.Fatal("Warning called twice in one TTL cycle")
+	case <-time.After(time.Second):
+		t.Fatal("State did not expire as expected")
+	}
+}
+
+func TestWarnOrder(t *testing.T) {
+	clock := timestate.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	warnCh := make(chan int, 100)
+	monitor := timestate.New(time.Second, time.Hour, make(chan int, 100),
+		timestate.WithClock[int, int](clock),
+		timestate.WithWarnThreshold[int, int](0.5, warnCh),
+	)
+
+	for i := range 50 {
+		monitor.WatchWithTTL(49-i, i, time.Duration(50-i)*time.Minute) // reverse order
+	}
+
+	monitor.Remove(10)
+	monitor.Pin(20)
+	monitor.WatchWithTTL(30, 1, 2*time.Hour) // re-armed later than the others
+	checkInvariants(t, monitor)
+
+	for range 30 {
+		clock.Advance(time.Minute)
+		monitor.Flush()
+	}
+
+	var warned []int
+	for len(warnCh) > 0 {
+		warned = append(warned, <-warnCh)
+	}
+
+	// 0 expires before the first check; 10 is removed, 20 pinned and 30
+	// warned later
+	var want []int
+	for i := 1; i < 50; i++ {
+		if i != 10 && i != 20 && i != 30 {
+			want = append(want, i)
+		}
+	}
+
+	if !slices.Equal(warned, want) {
+		t.Errorf("Unexpected warnings: %v", warned)
+	}
+
+	checkInvariants(t, monitor)
+}